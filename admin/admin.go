@@ -0,0 +1,301 @@
+// Package admin manages the feature toggles of a service in redis, in the same format read by the featuretoggle package:
+// a hash named after the service, with a field for each feature toggle and its metadata fields (like "<key>.type").
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/delivery-much/dm-go-ft/featuretoggle"
+	"github.com/go-redis/redis"
+)
+
+// ErrConflict means the feature toggles changed since the plan was made, so it was not applied
+var ErrConflict = errors.New("the feature toggles changed since the plan was made")
+
+// hashStore represents the redis hashes of the services
+type hashStore interface {
+	// hgetall returns every field of the hash
+	hgetall(key string) (map[string]string, error)
+	// update reads the hash and calls fn with it, then makes the write returned by fn in a single transaction,
+	// that fails with ErrConflict if the hash changes in the meantime
+	update(key string, fn func(current map[string]string) (write, error)) error
+	// lrange returns every item of the list
+	lrange(key string) ([]string, error)
+}
+
+// write represents a write of the feature toggles of a service, made in a single transaction
+type write struct {
+	// the fields set and deleted
+	set map[string]string
+	del []string
+	// the change stream where the write is appended, if any
+	stream *changeStream
+	// the history records of the write, appended to the history of the service (see HistoryKey)
+	history []Record
+}
+
+// DefaultChangeStreamLength is the approximate number of changes kept in the change stream, if no length is given
+const DefaultChangeStreamLength = 10000
+
+// changeStream represents the redis stream where the changes of the feature toggles are appended (see featuretoggle.ChangeStream)
+type changeStream struct {
+	name string
+	// the approximate number of changes kept in the stream
+	maxLen int64
+}
+
+// Client manages the feature toggles of a service in redis
+type Client struct {
+	db      hashStore
+	service string
+	stream  *changeStream
+	// who makes the writes, and why, recorded in the history
+	actor  string
+	reason string
+}
+
+// New returns a client that manages the feature toggles of the service, using the redis client.
+func New(client *redis.Client, service string) *Client {
+	return &Client{db: &redisStore{client}, service: service}
+}
+
+// WithChangeStream returns a copy of the client that also appends every write to the change stream of the service,
+// read by the services in change stream mode (see featuretoggle.Config.ChangeStream).
+// Only the last maxLen changes (approximately) are kept, or DefaultChangeStreamLength if maxLen is not positive.
+func (c *Client) WithChangeStream(maxLen int64) *Client {
+	if maxLen <= 0 {
+		maxLen = DefaultChangeStreamLength
+	}
+
+	cp := *c
+	cp.stream = &changeStream{name: featuretoggle.ChangeStream(c.service), maxLen: maxLen}
+	return &cp
+}
+
+// Service returns the name of the service managed by the client
+func (c *Client) Service() string {
+	return c.service
+}
+
+// Toggles returns the raw feature toggles of the service, as saved in redis (with their metadata fields).
+func (c *Client) Toggles() (map[string]string, error) {
+	raw, err := c.db.hgetall(c.service)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the feature toggles of %s: %w", c.service, err)
+	}
+	return raw, nil
+}
+
+// Plan returns the plan to make the feature toggles of the service match the desired raw feature toggles.
+func (c *Client) Plan(desired map[string]string) (Plan, error) {
+	raw, err := c.Toggles()
+	if err != nil {
+		return Plan{}, err
+	}
+
+	return NewPlan(c.service, raw, desired), nil
+}
+
+// Apply applies the plan to the feature toggles of the service in a single transaction (MULTI/EXEC),
+// incrementing their version (see featuretoggle.VersionField), so that the services see every change at once.
+//
+// returns ErrConflict, without applying any change, if any field changed since the plan was made.
+func (c *Client) Apply(p Plan) error {
+	if p.Service != c.service {
+		return fmt.Errorf("the plan is for the service %s, not %s", p.Service, c.service)
+	}
+	if p.Empty() {
+		return nil
+	}
+
+	return c.db.update(c.service, func(current map[string]string) (write, error) {
+		set := map[string]string{}
+		var del []string
+		for _, ch := range p.Changes {
+			old, ok := current[ch.Field]
+			if ok != (ch.Op != OpAdd) || old != ch.Old {
+				return write{}, fmt.Errorf("%w: %s", ErrConflict, ch.Field)
+			}
+
+			if ch.Op == OpRemove {
+				del = append(del, ch.Field)
+				continue
+			}
+			set[ch.Field] = ch.New
+		}
+
+		return c.write(current, set, del), nil
+	})
+}
+
+// Write sets and deletes fields of the feature toggles of the service (feature toggle keys or metadata fields, like "<key>.type")
+// in a single transaction (MULTI/EXEC), incrementing their version (see featuretoggle.VersionField),
+// so that the services see every change at once.
+//
+// Every feature toggle changed is checked before writing (see featuretoggle.Check), and nothing is written if any is invalid.
+// returns the new version of the feature toggles.
+func (c *Client) Write(set map[string]string, del ...string) (int64, error) {
+	var version int64
+	err := c.db.update(c.service, func(current map[string]string) (write, error) {
+		next := applyFields(current, set, del)
+
+		var errs []error
+		for _, key := range featuretoggle.Keys(next) {
+			if touched(current, next, key, set, del) {
+				if err := featuretoggle.Check(next, key); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+		if len(errs) > 0 {
+			return write{}, errors.Join(errs...)
+		}
+
+		version = versionOf(current) + 1
+		return c.write(current, set, del), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// Version returns the version of the feature toggles of the service (see featuretoggle.VersionField), zero if they were never versioned.
+func (c *Client) Version() (int64, error) {
+	raw, err := c.Toggles()
+	if err != nil {
+		return 0, err
+	}
+	return versionOf(raw), nil
+}
+
+// write returns the write of the fields to the current feature toggles, incrementing their version,
+// with the change stream of the client and the history records.
+func (c *Client) write(current map[string]string, set map[string]string, del []string) write {
+	version := versionOf(current) + 1
+
+	fields := make(map[string]string, len(set)+1)
+	for field, val := range set {
+		fields[field] = val
+	}
+	fields[featuretoggle.VersionField] = strconv.FormatInt(version, 10)
+
+	return write{
+		set:     fields,
+		del:     del,
+		stream:  c.stream,
+		history: c.records(current, applyFields(current, set, del), version),
+	}
+}
+
+// applyFields returns a copy of the raw feature toggles with the fields set and deleted.
+func applyFields(raw map[string]string, set map[string]string, del []string) map[string]string {
+	next := make(map[string]string, len(raw)+len(set))
+	for k, v := range raw {
+		next[k] = v
+	}
+	for _, field := range del {
+		delete(next, field)
+	}
+	for field, val := range set {
+		next[field] = val
+	}
+	return next
+}
+
+// touched checks if the feature toggle of the key, or any of its metadata fields, is set or deleted.
+func touched(current, next map[string]string, key string, set map[string]string, del []string) bool {
+	if _, ok := set[key]; ok {
+		return true
+	}
+	for _, field := range featuretoggle.MetadataFields(next, key) {
+		if _, ok := set[field]; ok {
+			return true
+		}
+	}
+
+	// the deleted fields are only found in the current feature toggles
+	fields := append([]string{key}, featuretoggle.MetadataFields(current, key)...)
+	for _, field := range fields {
+		if slices.Contains(del, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// versionOf returns the version of the raw feature toggles, zero if it was not found or is invalid.
+func versionOf(raw map[string]string) int64 {
+	v, _ := strconv.ParseInt(raw[featuretoggle.VersionField], 10, 64)
+	return v
+}
+
+// redisStore is the hashStore of a redis client
+type redisStore struct {
+	client *redis.Client
+}
+
+func (s *redisStore) hgetall(key string) (map[string]string, error) {
+	return s.client.HGetAll(key).Result()
+}
+
+func (s *redisStore) lrange(key string) ([]string, error) {
+	return s.client.LRange(key, 0, -1).Result()
+}
+
+func (s *redisStore) update(key string, fn func(map[string]string) (write, error)) error {
+	err := s.client.Watch(func(tx *redis.Tx) error {
+		current, err := tx.HGetAll(key).Result()
+		if err != nil {
+			return err
+		}
+
+		w, err := fn(current)
+		if err != nil {
+			return err
+		}
+
+		history := make([]interface{}, 0, len(w.history))
+		for _, r := range w.history {
+			b, err := json.Marshal(r)
+			if err != nil {
+				return err
+			}
+			history = append(history, string(b))
+		}
+
+		_, err = tx.Pipelined(func(pipe redis.Pipeliner) error {
+			if len(w.set) > 0 {
+				values := make(map[string]interface{}, len(w.set))
+				for k, v := range w.set {
+					values[k] = v
+				}
+				pipe.HMSet(key, values)
+			}
+			if len(w.del) > 0 {
+				pipe.HDel(key, w.del...)
+			}
+			if w.stream != nil {
+				pipe.XAdd(&redis.XAddArgs{
+					Stream:       w.stream.name,
+					MaxLenApprox: w.stream.maxLen,
+					Values:       featuretoggle.ChangeValues(versionOf(w.set), w.set, w.del),
+				})
+			}
+			if len(history) > 0 {
+				pipe.RPush(HistoryKey(key), history...)
+			}
+			return nil
+		})
+		return err
+	}, key)
+
+	if err == redis.TxFailedErr {
+		return ErrConflict
+	}
+	return err
+}
@@ -0,0 +1,219 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/delivery-much/dm-go-ft/featuretoggle"
+)
+
+// fakeStore is a hashStore that keeps the hashes in memory
+type fakeStore struct {
+	hashes map[string]map[string]string
+	// the entries appended to the streams, by stream
+	streams map[string][]map[string]interface{}
+	// the items of the lists, by list
+	lists map[string][]string
+}
+
+func newFakeStore(service string, hash map[string]string) *fakeStore {
+	f := &fakeStore{hashes: map[string]map[string]string{}, streams: map[string][]map[string]interface{}{}, lists: map[string][]string{}}
+	f.hashes[service] = map[string]string{}
+	for k, v := range hash {
+		f.hashes[service][k] = v
+	}
+	return f
+}
+
+func (f *fakeStore) hgetall(key string) (map[string]string, error) {
+	raw := map[string]string{}
+	for k, v := range f.hashes[key] {
+		raw[k] = v
+	}
+	return raw, nil
+}
+
+func (f *fakeStore) lrange(key string) ([]string, error) {
+	return f.lists[key], nil
+}
+
+func (f *fakeStore) update(key string, fn func(map[string]string) (write, error)) error {
+	current, _ := f.hgetall(key)
+	w, err := fn(current)
+	if err != nil {
+		return err
+	}
+
+	if f.hashes[key] == nil {
+		f.hashes[key] = map[string]string{}
+	}
+	for k, v := range w.set {
+		f.hashes[key][k] = v
+	}
+	for _, k := range w.del {
+		delete(f.hashes[key], k)
+	}
+	if w.stream != nil {
+		f.streams[w.stream.name] = append(f.streams[w.stream.name], featuretoggle.ChangeValues(versionOf(w.set), w.set, w.del))
+	}
+	for _, r := range w.history {
+		b, _ := json.Marshal(r)
+		f.lists[HistoryKey(key)] = append(f.lists[HistoryKey(key)], string(b))
+	}
+	return nil
+}
+
+func TestApply(t *testing.T) {
+	current := map[string]string{
+		"MyBool":       "true",
+		"MyBool.type":  "boolean",
+		"MyOld":        "value",
+		"MyOld.type":   "string",
+		"MyLimit":      "5",
+		"MyLimit.type": "integer",
+	}
+	desired := map[string]string{
+		"MyBool":       "false",
+		"MyBool.type":  "boolean",
+		"MyLimit":      "5",
+		"MyLimit.type": "integer",
+		"MyNew":        "1m",
+		"MyNew.type":   "duration",
+	}
+
+	t.Run("Should apply the plan", func(t *testing.T) {
+		fake := newFakeStore("my-service", current)
+		c := &Client{db: fake, service: "my-service"}
+
+		p, err := c.Plan(desired)
+		if err != nil {
+			t.Fatalf("Should have made the plan, returned %v", err)
+		}
+
+		err = c.Apply(p)
+		if err != nil {
+			t.Fatalf("Should have applied the plan, returned %v", err)
+		}
+
+		raw, _ := c.Toggles()
+		if len(raw) != len(desired)+1 || raw[featuretoggle.VersionField] != "1" {
+			t.Fatalf("Should have the desired feature toggles with the version, found %v", raw)
+		}
+		for k, v := range desired {
+			if raw[k] != v {
+				t.Errorf("Should have %s = %q, found %q", k, v, raw[k])
+			}
+		}
+
+		p, _ = c.Plan(desired)
+		if !p.Empty() {
+			t.Errorf("Should not have any change after the plan was applied, found %+v", p.Changes)
+		}
+	})
+	t.Run("Should not apply stale plans", func(t *testing.T) {
+		fake := newFakeStore("my-service", current)
+		c := &Client{db: fake, service: "my-service"}
+
+		p, _ := c.Plan(desired)
+		fake.hashes["my-service"] = map[string]string{"MyBool": "false", "MyBool.type": "boolean"}
+
+		err := c.Apply(p)
+		if !errors.Is(err, ErrConflict) {
+			t.Errorf("Should have returned ErrConflict, returned %v", err)
+		}
+		if len(fake.hashes["my-service"]) != 2 {
+			t.Errorf("Should not have applied any change, found %v", fake.hashes["my-service"])
+		}
+	})
+	t.Run("Should not apply plans of other services", func(t *testing.T) {
+		c := &Client{db: newFakeStore("my-service", current), service: "my-service"}
+
+		err := c.Apply(NewPlan("other-service", current, desired))
+		if err == nil {
+			t.Errorf("Should have refused the plan of another service")
+		}
+	})
+}
+
+func TestWrite(t *testing.T) {
+	fake := newFakeStore("my-service", map[string]string{
+		"MyBool":       "true",
+		"MyBool.type":  "boolean",
+		"MyLimit":      "5",
+		"MyLimit.type": "integer",
+		"MyOld":        "value",
+		"MyOld.type":   "string",
+	})
+	c := &Client{db: fake, service: "my-service"}
+
+	version, err := c.Write(map[string]string{"MyBool": "false", "MyLimit": "6", "MyLimit.max": "10"}, "MyOld", "MyOld.type")
+	if err != nil || version != 1 {
+		t.Fatalf("Should have written the fields with the first version, returned %v, %v", version, err)
+	}
+
+	raw := fake.hashes["my-service"]
+	if raw["MyBool"] != "false" || raw["MyLimit.max"] != "10" || raw[featuretoggle.VersionField] != "1" {
+		t.Errorf("Should have written every field with the version, found %v", raw)
+	}
+	if _, ok := raw["MyOld"]; ok {
+		t.Errorf("Should have deleted the fields, found %v", raw)
+	}
+
+	_, err = c.Write(map[string]string{"MyBool": "true", "MyLimit": "20"})
+	if !errors.Is(err, featuretoggle.ErrConstraintViolation) {
+		t.Errorf("Should have refused the invalid value, returned %v", err)
+	}
+	if raw := fake.hashes["my-service"]; raw["MyBool"] != "false" || raw[featuretoggle.VersionField] != "1" {
+		t.Errorf("Should not have written any field, found %v", raw)
+	}
+
+	version, err = c.Write(map[string]string{"MyBool": "true"})
+	if err != nil || version != 2 {
+		t.Errorf("Should have incremented the version, returned %v, %v", version, err)
+	}
+	if v, _ := c.Version(); v != 2 {
+		t.Errorf("Should have returned the version, returned %v", v)
+	}
+
+	_, err = c.Write(nil, "MyBool.type")
+	if !errors.Is(err, featuretoggle.ErrTypeMissing) {
+		t.Errorf("Should have refused to delete the type, returned %v", err)
+	}
+	if raw := fake.hashes["my-service"]; raw["MyBool.type"] != "boolean" || raw[featuretoggle.VersionField] != "2" {
+		t.Errorf("Should not have deleted any field, found %v", raw)
+	}
+
+	p, _ := c.Plan(map[string]string{"MyBool": "true", "MyBool.type": "boolean", "MyLimit": "6", "MyLimit.type": "integer", "MyLimit.max": "10"})
+	if !p.Empty() {
+		t.Errorf("Should not have planned the version, planned %+v", p.Changes)
+	}
+}
+
+func TestWithChangeStream(t *testing.T) {
+	fake := newFakeStore("my-service", map[string]string{"MyBool": "true", "MyBool.type": "boolean"})
+	c := (&Client{db: fake, service: "my-service"}).WithChangeStream(0)
+
+	_, err := c.Write(map[string]string{"MyBool": "false"}, "MyOld")
+	if err != nil {
+		t.Fatalf("Should have written the fields, returned %v", err)
+	}
+	err = c.Apply(NewPlan("my-service", fake.hashes["my-service"], map[string]string{"MyString": "value", "MyString.type": "string"}))
+	if err != nil {
+		t.Fatalf("Should have applied the plan, returned %v", err)
+	}
+
+	entries := fake.streams[featuretoggle.ChangeStream("my-service")]
+	if len(entries) != 2 {
+		t.Fatalf("Should have appended a change for each write, appended %v", entries)
+	}
+	if entries[0]["version"] != "1" || entries[0]["set:MyBool"] != "false" || entries[0]["del:MyOld"] != "" {
+		t.Errorf("Should have appended the first write, appended %v", entries[0])
+	}
+	if entries[1]["version"] != "2" || entries[1]["set:MyString"] != "value" || entries[1]["del:MyBool"] != "" {
+		t.Errorf("Should have appended the plan, appended %v", entries[1])
+	}
+	if c.stream.maxLen != DefaultChangeStreamLength {
+		t.Errorf("Should have used the default length, used %d", c.stream.maxLen)
+	}
+}
@@ -0,0 +1,77 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// backupVersion is the version of the backup format written by Backup.Write
+const backupVersion = 1
+
+// Backup represents the raw feature toggles of a service, exported to a portable file.
+// The feature toggles are kept exactly as saved in redis, including invalid values and orphan metadata fields.
+type Backup struct {
+	Version    int               `json:"version"`
+	Service    string            `json:"service"`
+	ExportedAt time.Time         `json:"exported_at"`
+	Toggles    map[string]string `json:"toggles"`
+}
+
+// NewBackup returns a backup of the raw feature toggles of the service.
+func NewBackup(service string, raw map[string]string) *Backup {
+	return &Backup{Version: backupVersion, Service: service, ExportedAt: time.Now().UTC(), Toggles: raw}
+}
+
+// Export returns a backup of the feature toggles of the service.
+func (c *Client) Export() (*Backup, error) {
+	raw, err := c.Toggles()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewBackup(c.service, raw), nil
+}
+
+// Restore replaces the feature toggles of the service with the ones of the backup, in a single transaction (see Apply).
+// The backup may be from another service, redis or database.
+// returns the applied plan.
+func (c *Client) Restore(b *Backup) (Plan, error) {
+	p, err := c.Plan(b.Toggles)
+	if err != nil {
+		return Plan{}, err
+	}
+
+	err = c.Apply(p)
+	if err != nil {
+		return Plan{}, err
+	}
+	return p, nil
+}
+
+// ReadBackup reads a backup written by Backup.Write.
+func ReadBackup(r io.Reader) (*Backup, error) {
+	var b Backup
+	err := json.NewDecoder(r).Decode(&b)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup: %w", err)
+	}
+
+	switch {
+	case b.Version != backupVersion:
+		return nil, fmt.Errorf("invalid backup: unsupported version %d", b.Version)
+	case b.Service == "":
+		return nil, fmt.Errorf("invalid backup: the service is required")
+	case b.Toggles == nil:
+		return nil, fmt.Errorf("invalid backup: the toggles are required")
+	}
+	return &b, nil
+}
+
+// Write writes the backup as indented json.
+func (b *Backup) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b)
+}
@@ -0,0 +1,54 @@
+package admin
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBackup(t *testing.T) {
+	staging := &Client{db: newFakeStore("my-service", map[string]string{
+		"MyBool":      "true",
+		"MyBool.type": "boolean",
+		"Orphan.type": "string",
+	}), service: "my-service"}
+	production := &Client{db: newFakeStore("other-service", map[string]string{
+		"MyOld": "value",
+	}), service: "other-service"}
+
+	b, err := staging.Export()
+	if err != nil {
+		t.Fatalf("Should have exported the feature toggles, returned %v", err)
+	}
+
+	var buf bytes.Buffer
+	err = b.Write(&buf)
+	if err != nil {
+		t.Fatalf("Should have written the backup, returned %v", err)
+	}
+
+	read, err := ReadBackup(&buf)
+	if err != nil {
+		t.Fatalf("Should have read the backup, returned %v", err)
+	}
+	if read.Service != "my-service" || read.ExportedAt.IsZero() || len(read.Toggles) != 3 {
+		t.Fatalf("Should have read the backup exactly as written, read %+v", read)
+	}
+
+	p, err := production.Restore(read)
+	if err != nil {
+		t.Fatalf("Should have restored the backup, returned %v", err)
+	}
+	if add, _, remove := p.Count(); add != 3 || remove != 1 {
+		t.Errorf("Should have returned the applied plan, returned %+v", p)
+	}
+
+	raw, _ := production.Toggles()
+	if len(Diff(raw, read.Toggles)) != 0 || len(raw) != 4 {
+		t.Errorf("Should have replaced the feature toggles with the backup, found %v", raw)
+	}
+
+	_, err = ReadBackup(bytes.NewBufferString(`{"version": 1, "toggles": {}}`))
+	if err == nil {
+		t.Errorf("Should have rejected the backup without service")
+	}
+}
@@ -0,0 +1,125 @@
+package admin
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/delivery-much/dm-go-ft/featuretoggle"
+)
+
+// DiffKind represents a kind of difference of a feature toggle between two services
+type DiffKind string
+
+const (
+	// DiffOnlyLeft means the feature toggle was found only in the left service
+	DiffOnlyLeft DiffKind = "only_left"
+	// DiffOnlyRight means the feature toggle was found only in the right service
+	DiffOnlyRight DiffKind = "only_right"
+	// DiffType means the "<key>.type" field is different
+	DiffType DiffKind = "type"
+	// DiffValue means the value is different
+	DiffValue DiffKind = "value"
+	// DiffMetadata means any other metadata field is different (like "<key>.max")
+	DiffMetadata DiffKind = "metadata"
+)
+
+// KeyDiff represents the differences of a feature toggle between two services
+type KeyDiff struct {
+	Key string `json:"key"`
+	// the differences found
+	Kinds []DiffKind `json:"kinds"`
+	// the feature toggle in each service, nil if it was not found
+	Left  *Toggle `json:"left"`
+	Right *Toggle `json:"right"`
+}
+
+// Diff compares the raw feature toggles of two services (like the same service in two environments) key by key,
+// with their metadata fields. returns the feature toggles that differ, sorted by key.
+//
+// Metadata fields without a feature toggle (like an orphan "<key>.type") are compared as feature toggles.
+func Diff(left map[string]string, right map[string]string) []KeyDiff {
+	leftKeys, rightKeys := keySet(left), keySet(right)
+
+	var keys []string
+	for key := range leftKeys {
+		keys = append(keys, key)
+	}
+	for key := range rightKeys {
+		if !leftKeys[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	diffs := []KeyDiff{}
+	for _, key := range keys {
+		d := KeyDiff{Key: key}
+		if leftKeys[key] {
+			d.Left = toggleOf(left, key)
+		}
+		if rightKeys[key] {
+			d.Right = toggleOf(right, key)
+		}
+
+		switch {
+		case d.Right == nil:
+			d.Kinds = []DiffKind{DiffOnlyLeft}
+		case d.Left == nil:
+			d.Kinds = []DiffKind{DiffOnlyRight}
+		default:
+			if d.Left.Type != d.Right.Type {
+				d.Kinds = append(d.Kinds, DiffType)
+			}
+			if d.Left.Value != d.Right.Value {
+				d.Kinds = append(d.Kinds, DiffValue)
+			}
+			if !equalMetadata(d.Left.Metadata, d.Right.Metadata) {
+				d.Kinds = append(d.Kinds, DiffMetadata)
+			}
+		}
+
+		if len(d.Kinds) > 0 {
+			diffs = append(diffs, d)
+		}
+	}
+
+	return diffs
+}
+
+// keySet returns the feature toggle keys of the raw feature toggles, without their metadata fields.
+func keySet(raw map[string]string) map[string]bool {
+	keys := map[string]bool{}
+	for _, key := range featuretoggle.Keys(raw) {
+		keys[key] = true
+	}
+	return keys
+}
+
+// toggleOf returns the feature toggle of the key in the raw feature toggles, with its metadata fields.
+func toggleOf(raw map[string]string, key string) *Toggle {
+	t := &Toggle{Type: raw[key+".type"], Value: raw[key]}
+	for _, field := range featuretoggle.MetadataFields(raw, key) {
+		name := strings.TrimPrefix(field, key+".")
+		if name == "type" {
+			continue
+		}
+		if t.Metadata == nil {
+			t.Metadata = map[string]string{}
+		}
+		t.Metadata[name] = raw[field]
+	}
+	return t
+}
+
+// equalMetadata checks if the metadata fields are equal.
+func equalMetadata(a map[string]string, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, val := range a {
+		if v, ok := b[name]; !ok || v != val {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package admin
+
+import (
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	left := map[string]string{
+		"MyBool":       "true",
+		"MyBool.type":  "boolean",
+		"MyLimit":      "5",
+		"MyLimit.type": "integer",
+		"MyLimit.max":  "10",
+		"MySame":       "value",
+		"MySame.type":  "string",
+		"MyOld":        "value",
+		"Orphan":       "1",
+		"Orphan.type":  "integer",
+	}
+	right := map[string]string{
+		"MyBool":       "true",
+		"MyBool.type":  "string",
+		"MyLimit":      "6",
+		"MyLimit.type": "integer",
+		"MySame":       "value",
+		"MySame.type":  "string",
+		"MyNew":        "value",
+		"Orphan.type":  "integer",
+	}
+
+	expected := []KeyDiff{
+		{Key: "MyBool", Kinds: []DiffKind{DiffType}},
+		{Key: "MyLimit", Kinds: []DiffKind{DiffValue, DiffMetadata}},
+		{Key: "MyNew", Kinds: []DiffKind{DiffOnlyRight}},
+		{Key: "MyOld", Kinds: []DiffKind{DiffOnlyLeft}},
+		{Key: "Orphan", Kinds: []DiffKind{DiffOnlyLeft}},
+		{Key: "Orphan.type", Kinds: []DiffKind{DiffOnlyRight}},
+	}
+
+	diffs := Diff(left, right)
+	if len(diffs) != len(expected) {
+		t.Fatalf("Should have found %d differences, found %+v", len(expected), diffs)
+	}
+	for i, e := range expected {
+		d := diffs[i]
+		if d.Key != e.Key || len(d.Kinds) != len(e.Kinds) {
+			t.Errorf("Should have found %+v, found %+v", e, d)
+			continue
+		}
+		for j := range e.Kinds {
+			if d.Kinds[j] != e.Kinds[j] {
+				t.Errorf("Should have found %+v, found %+v", e, d)
+			}
+		}
+	}
+
+	if d := diffs[1]; d.Left.Metadata["max"] != "10" || d.Right.Metadata != nil || d.Left.Type != "integer" {
+		t.Errorf("Should have returned the feature toggles of both sides, returned %+v %+v", d.Left, d.Right)
+	}
+	if d := diffs[2]; d.Left != nil || d.Right.Value != "value" {
+		t.Errorf("Should have returned nil for the missing side, returned %+v %+v", d.Left, d.Right)
+	}
+}
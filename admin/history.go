@@ -0,0 +1,139 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/delivery-much/dm-go-ft/featuretoggle"
+)
+
+// Record represents a change of a field of the feature toggles of a service, recorded in its history (see HistoryKey)
+type Record struct {
+	Change
+	// the feature toggle of the field (the field itself, or the key of a metadata field like "<key>.type")
+	Key string `json:"key"`
+	// the declared type of the feature toggle after the change (or before, if it was removed)
+	Type string `json:"type,omitempty"`
+	// the declared type of the feature toggle before the change, empty if it had no type
+	OldType string `json:"old_type,omitempty"`
+	// who made the change, and why (see WithAuthor)
+	Actor  string `json:"actor,omitempty"`
+	Reason string `json:"reason,omitempty"`
+	// the version of the feature toggles after the change (see featuretoggle.VersionField)
+	Version int64     `json:"version"`
+	Time    time.Time `json:"time"`
+}
+
+// HistoryKey returns the name of the redis list with the history of the feature toggles of the service.
+// Every write made through the admin package appends its records (see Record) to the list, in the same transaction, as json.
+func HistoryKey(service string) string {
+	return service + ":history"
+}
+
+// WithAuthor returns a copy of the client that records who makes the writes, and why, in the history of the service.
+func (c *Client) WithAuthor(actor string, reason string) *Client {
+	cp := *c
+	cp.actor, cp.reason = actor, reason
+	return &cp
+}
+
+// History returns the history of the feature toggle of the key, with the changes of its metadata fields, oldest first.
+// returns the history of every feature toggle if the key is empty.
+func (c *Client) History(key string) ([]Record, error) {
+	items, err := c.db.lrange(HistoryKey(c.service))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the history of %s: %w", c.service, err)
+	}
+
+	records := []Record{}
+	for _, item := range items {
+		var r Record
+		err := json.Unmarshal([]byte(item), &r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid history record %q: %w", item, err)
+		}
+		if key == "" || r.Key == key {
+			records = append(records, r)
+		}
+	}
+	return records, nil
+}
+
+// RollbackPlan returns the plan to roll the feature toggle of the key back to its value at the version (see Revert).
+func (c *Client) RollbackPlan(key string, version int64) (Plan, error) {
+	raw, err := c.Toggles()
+	if err != nil {
+		return Plan{}, err
+	}
+	records, err := c.History(key)
+	if err != nil {
+		return Plan{}, err
+	}
+
+	return NewPlan(c.service, raw, Revert(raw, records, key, version)), nil
+}
+
+// Revert returns a copy of the raw feature toggles with the feature toggle of the key (and its metadata fields)
+// as it was at the version, undoing its changes recorded after the version, newest first.
+func Revert(raw map[string]string, records []Record, key string, version int64) map[string]string {
+	desired := applyFields(raw, nil, nil)
+	for i := len(records) - 1; i >= 0; i-- {
+		r := records[i]
+		if r.Key != key || r.Version <= version {
+			continue
+		}
+
+		if r.Op == OpAdd {
+			delete(desired, r.Field)
+			continue
+		}
+		desired[r.Field] = r.Old
+	}
+	return desired
+}
+
+// records returns the history records of the change of the current feature toggles into the next ones, sorted by field.
+func (c *Client) records(current map[string]string, next map[string]string, version int64) []Record {
+	keys := fieldKeys(current)
+	for field, key := range fieldKeys(next) {
+		keys[field] = key
+	}
+
+	now := time.Now().UTC()
+	var records []Record
+	for _, ch := range NewPlan(c.service, current, next).Changes {
+		key, ok := keys[ch.Field]
+		if !ok {
+			key = ch.Field
+		}
+
+		typ := next[key+".type"]
+		if typ == "" {
+			typ = current[key+".type"]
+		}
+
+		records = append(records, Record{
+			Change:  ch,
+			Key:     key,
+			Type:    typ,
+			OldType: current[key+".type"],
+			Actor:   c.actor,
+			Reason:  c.reason,
+			Version: version,
+			Time:    now,
+		})
+	}
+	return records
+}
+
+// fieldKeys returns the feature toggle of each metadata field of the raw feature toggles.
+func fieldKeys(raw map[string]string) map[string]string {
+	keys := map[string]string{}
+	for _, key := range featuretoggle.Keys(raw) {
+		for _, field := range featuretoggle.MetadataFields(raw, key) {
+			keys[field] = key
+		}
+	}
+	return keys
+}
@@ -0,0 +1,99 @@
+package admin
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	fake := newFakeStore("my-service", map[string]string{
+		"CouponEngine":      "true",
+		"CouponEngine.type": "boolean",
+		"MyLimit":           "5",
+		"MyLimit.type":      "integer",
+	})
+	c := (&Client{db: fake, service: "my-service"}).WithAuthor("alice", "incident 42")
+
+	_, err := c.Write(map[string]string{"CouponEngine": "false", "MyLimit.max": "10"})
+	if err != nil {
+		t.Fatalf("Should have written the fields, returned %v", err)
+	}
+	bob := c.WithAuthor("bob", "")
+	err = bob.Apply(NewPlan("my-service", fake.hashes["my-service"], map[string]string{"MyLimit": "6", "MyLimit.type": "integer", "MyLimit.max": "10"}))
+	if err != nil {
+		t.Fatalf("Should have applied the plan, returned %v", err)
+	}
+
+	records, err := c.History("CouponEngine")
+	if err != nil {
+		t.Fatalf("Should have returned the history, returned %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Should have returned the changes of the key, returned %+v", records)
+	}
+
+	first := records[0]
+	if first.Field != "CouponEngine" || first.Op != OpChange || first.Old != "true" || first.New != "false" ||
+		first.Type != "boolean" || first.OldType != "boolean" || first.Actor != "alice" || first.Reason != "incident 42" || first.Version != 1 || first.Time.IsZero() {
+		t.Errorf("Should have recorded the change, recorded %+v", first)
+	}
+	if records[1].Op != OpRemove || records[1].Actor != "bob" || records[1].Version != 2 || records[1].Type != "boolean" {
+		t.Errorf("Should have recorded the removal, recorded %+v", records[1])
+	}
+	if records[2].Field != "CouponEngine.type" {
+		t.Errorf("Should have recorded the metadata fields with their key, recorded %+v", records[2])
+	}
+
+	all, _ := c.History("")
+	if len(all) != 5 {
+		t.Errorf("Should have returned the history of every key, returned %+v", all)
+	}
+	if all[1].Field != "MyLimit.max" || all[1].Key != "MyLimit" {
+		t.Errorf("Should have recorded the metadata fields with their key, recorded %+v", all[1])
+	}
+}
+
+func TestRollback(t *testing.T) {
+	fake := newFakeStore("my-service", map[string]string{"CouponEngine": "true", "CouponEngine.type": "boolean"})
+	c := &Client{db: fake, service: "my-service"}
+
+	for _, set := range []map[string]string{
+		{"CouponEngine": "false"},
+		{"CouponEngine.fallback": "last_known_good"},
+		{"Other": "value", "Other.type": "string"},
+	} {
+		if _, err := c.Write(set); err != nil {
+			t.Fatalf("Should have written the fields, returned %v", err)
+		}
+	}
+
+	p, err := c.RollbackPlan("CouponEngine", 0)
+	if err != nil {
+		t.Fatalf("Should have planned the rollback, returned %v", err)
+	}
+	expected := []Change{
+		{Op: OpChange, Field: "CouponEngine", Old: "false", New: "true"},
+		{Op: OpRemove, Field: "CouponEngine.fallback", Old: "last_known_good"},
+	}
+	if !reflect.DeepEqual(p.Changes, expected) {
+		t.Fatalf("Expected the changes %+v, planned %+v", expected, p.Changes)
+	}
+
+	err = c.Apply(p)
+	if err != nil {
+		t.Fatalf("Should have rolled back, returned %v", err)
+	}
+	if raw := fake.hashes["my-service"]; raw["CouponEngine"] != "true" || raw["Other"] != "value" {
+		t.Errorf("Should have rolled back only the key, found %v", raw)
+	}
+
+	records, _ := c.History("CouponEngine")
+	if last := records[len(records)-1]; last.Version != 4 {
+		t.Errorf("Should have recorded the rollback, recorded %+v", last)
+	}
+
+	p, _ = c.RollbackPlan("CouponEngine", 1)
+	if len(p.Changes) != 1 || p.Changes[0].New != "false" {
+		t.Errorf("Should have planned the rollback to the version 1, planned %+v", p.Changes)
+	}
+}
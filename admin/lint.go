@@ -0,0 +1,284 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/delivery-much/dm-go-ft/featuretoggle"
+)
+
+// LintRule represents a kind of problem found in the feature toggles of a service
+type LintRule string
+
+const (
+	// LintOrphanMetadata means a metadata field (like "<key>.type") has no feature toggle, or its value is empty.
+	// Fixed by removing the field.
+	LintOrphanMetadata LintRule = "orphan_metadata"
+	// LintMissingType means a feature toggle has no "<key>.type" field.
+	// Fixed by adding the type inferred from the value.
+	LintMissingType LintRule = "missing_type"
+	// LintUnknownType means the "<key>.type" field is not one of the featuretoggle.Types.
+	// Fixed by replacing it with the type it is an alias of (like "bool" for "boolean"), if any.
+	LintUnknownType LintRule = "unknown_type"
+	// LintNonCanonicalBoolean means a boolean value is not "true" or "false" (like "TRUE" or "1").
+	// Fixed by replacing it with "true" or "false".
+	LintNonCanonicalBoolean LintRule = "non_canonical_boolean"
+	// LintInvalidValue means the value could not be parsed into its type. Can not be fixed automatically.
+	LintInvalidValue LintRule = "invalid_value"
+	// LintConstraintViolation means the value violates its constraints. Can not be fixed automatically.
+	LintConstraintViolation LintRule = "constraint_violation"
+)
+
+// LintProblem represents a problem found in a field of the feature toggles of a service
+type LintProblem struct {
+	Rule LintRule `json:"rule"`
+	// the field with the problem (a feature toggle key, or a metadata field like "<key>.type")
+	Field   string `json:"field"`
+	Message string `json:"message"`
+	// the change that fixes the problem, nil if it can not be fixed automatically
+	Fix *Change `json:"fix,omitempty"`
+}
+
+// typeAliases are the types commonly used by mistake in the "<key>.type" field, and the types they mean
+var typeAliases = map[string]string{
+	"bool":      "boolean",
+	"int":       "integer",
+	"int64":     "integer",
+	"float":     "number",
+	"float64":   "number",
+	"double":    "number",
+	"decimal":   "number",
+	"str":       "string",
+	"text":      "string",
+	"date":      "datetime",
+	"time":      "datetime",
+	"timestamp": "datetime",
+	"array":     "list",
+	"slice":     "list",
+	"object":    "json",
+	"map":       "json",
+}
+
+/*
+Lint checks the raw feature toggles of a service (in the format saved in redis) for the problems described by the LintRule constants.
+The values are parsed with the same rules used by the accessors (see featuretoggle.Check).
+
+returns the problems found, sorted by field.
+*/
+func Lint(raw map[string]string) []LintProblem {
+	problems := []LintProblem{}
+	for _, key := range featuretoggle.Keys(raw) {
+		if field, ok := orphan(raw, key); ok {
+			problems = append(problems, LintProblem{
+				Rule:    LintOrphanMetadata,
+				Field:   field,
+				Message: "the metadata field has no feature toggle",
+				Fix:     &Change{Op: OpRemove, Field: field, Old: raw[field]},
+			})
+			continue
+		}
+
+		if strings.TrimSpace(raw[key]) == "" {
+			for _, field := range featuretoggle.MetadataFields(raw, key) {
+				problems = append(problems, LintProblem{
+					Rule:    LintOrphanMetadata,
+					Field:   field,
+					Message: "the feature toggle of the metadata field is empty",
+					Fix:     &Change{Op: OpRemove, Field: field, Old: raw[field]},
+				})
+			}
+			continue
+		}
+
+		problems = append(problems, lintToggle(raw, key)...)
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Field < problems[j].Field
+	})
+	return problems
+}
+
+// lintToggle checks the type and the value of a feature toggle.
+// The value is checked against the type it will have after the type is fixed.
+func lintToggle(raw map[string]string, key string) []LintProblem {
+	var problems []LintProblem
+
+	typeField, val := key+".type", raw[key]
+	typ, ok := raw[typeField]
+	switch {
+	case !ok || strings.TrimSpace(typ) == "":
+		inferred := inferType(val)
+		problems = append(problems, LintProblem{
+			Rule:    LintMissingType,
+			Field:   key,
+			Message: fmt.Sprintf("the feature toggle has no type, inferred %s from the value", inferred),
+			Fix:     &Change{Op: OpAdd, Field: typeField, Old: typ, New: inferred},
+		})
+		if ok {
+			problems[0].Fix.Op = OpChange
+		}
+		typ = inferred
+	case !contains(featuretoggle.Types, typ):
+		p := LintProblem{Rule: LintUnknownType, Field: typeField, Message: fmt.Sprintf("unknown type %s", typ)}
+		alias, ok := typeAlias(typ)
+		if !ok {
+			return append(problems, p)
+		}
+
+		p.Message = fmt.Sprintf("unknown type %s, did you mean %s?", typ, alias)
+		p.Fix = &Change{Op: OpChange, Field: typeField, Old: typ, New: alias}
+		problems = append(problems, p)
+		typ = alias
+	}
+
+	if typ == "boolean" {
+		if b, err := strconv.ParseBool(val); err == nil && strconv.FormatBool(b) != val {
+			problems = append(problems, LintProblem{
+				Rule:    LintNonCanonicalBoolean,
+				Field:   key,
+				Message: fmt.Sprintf("the boolean value %s should be %t", val, b),
+				Fix:     &Change{Op: OpChange, Field: key, Old: val, New: strconv.FormatBool(b)},
+			})
+			val = strconv.FormatBool(b)
+		}
+	}
+
+	fixed := make(map[string]string, len(raw))
+	for k, v := range raw {
+		fixed[k] = v
+	}
+	fixed[key], fixed[typeField] = val, typ
+
+	err := featuretoggle.Check(fixed, key)
+	switch {
+	case errors.Is(err, featuretoggle.ErrParse):
+		problems = append(problems, LintProblem{Rule: LintInvalidValue, Field: key, Message: reason(err, key)})
+	case errors.Is(err, featuretoggle.ErrConstraintViolation):
+		problems = append(problems, LintProblem{Rule: LintConstraintViolation, Field: key, Message: reason(err, key)})
+	}
+
+	return problems
+}
+
+// orphan checks if the key is actually a metadata field (like "<key>.type") of a missing feature toggle.
+// Keys that have their own type (like "checkout.type" with a "checkout.type.type" field) are feature toggles.
+func orphan(raw map[string]string, key string) (string, bool) {
+	if _, ok := raw[key+".type"]; ok {
+		return "", false
+	}
+
+	i := strings.LastIndex(key, ".")
+	if i < 0 {
+		return "", false
+	}
+
+	base := key[:i]
+	return key, contains(featuretoggle.MetadataFields(map[string]string{key: ""}, base), key)
+}
+
+// typeAlias returns the type the unknown type is an alias of, if any.
+func typeAlias(typ string) (string, bool) {
+	lower := strings.ToLower(strings.TrimSpace(typ))
+	if contains(featuretoggle.Types, lower) {
+		return lower, true
+	}
+
+	alias, ok := typeAliases[lower]
+	return alias, ok
+}
+
+// inferType infers the type of a feature toggle from its value.
+func inferType(val string) string {
+	if _, err := strconv.ParseInt(val, 10, 64); err == nil {
+		return "integer"
+	}
+	if _, err := strconv.ParseFloat(val, 64); err == nil {
+		return "number"
+	}
+	if _, err := strconv.ParseBool(val); err == nil {
+		return "boolean"
+	}
+	if _, err := time.Parse(time.RFC3339, val); err == nil {
+		return "datetime"
+	}
+	if _, err := time.ParseDuration(val); err == nil {
+		return "duration"
+	}
+
+	var v any
+	if json.Unmarshal([]byte(val), &v) == nil {
+		switch v.(type) {
+		case []any:
+			return "list"
+		case map[string]any:
+			return "json"
+		}
+	}
+
+	return "string"
+}
+
+// reason returns the error message without the key.
+func reason(err error, key string) string {
+	return strings.TrimPrefix(err.Error(), fmt.Sprintf("feature toggle %s: ", key))
+}
+
+// FixPlan returns the plan that fixes every problem of the raw feature toggles of the service that can be fixed automatically.
+func FixPlan(service string, raw map[string]string, problems []LintProblem) Plan {
+	fixed := make(map[string]string, len(raw))
+	for k, v := range raw {
+		fixed[k] = v
+	}
+
+	for _, p := range problems {
+		switch {
+		case p.Fix == nil:
+		case p.Fix.Op == OpRemove:
+			delete(fixed, p.Fix.Field)
+		default:
+			fixed[p.Fix.Field] = p.Fix.New
+		}
+	}
+
+	return NewPlan(service, raw, fixed)
+}
+
+// Lint checks the feature toggles of the service (see Lint).
+func (c *Client) Lint() ([]LintProblem, error) {
+	raw, err := c.Toggles()
+	if err != nil {
+		return nil, err
+	}
+
+	return Lint(raw), nil
+}
+
+// Fix fixes every problem of the feature toggles of the service that can be fixed automatically, in a single transaction (see Apply).
+// returns the applied plan, and the problems that could not be fixed.
+func (c *Client) Fix() (Plan, []LintProblem, error) {
+	raw, err := c.Toggles()
+	if err != nil {
+		return Plan{}, nil, err
+	}
+
+	problems := Lint(raw)
+	p := FixPlan(c.service, raw, problems)
+	err = c.Apply(p)
+	if err != nil {
+		return Plan{}, nil, err
+	}
+
+	var unfixed []LintProblem
+	for _, pr := range problems {
+		if pr.Fix == nil {
+			unfixed = append(unfixed, pr)
+		}
+	}
+	return p, unfixed, nil
+}
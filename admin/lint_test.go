@@ -0,0 +1,97 @@
+package admin
+
+import (
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	raw := map[string]string{
+		"MyBool":             "1",
+		"MyBool.type":        "boolean",
+		"MyFlag":             "True",
+		"MyFlag.type":        "Boolean",
+		"MyInt":              "5",
+		"MyInt.type":         "int",
+		"MyWeird":            "5",
+		"MyWeird.type":       "whatever",
+		"MyNumber":           "ten",
+		"MyNumber.type":      "number",
+		"MyLimit":            "20",
+		"MyLimit.type":       "integer",
+		"MyLimit.max":        "10",
+		"MyUntyped":          "1m30s",
+		"MyEmpty":            "",
+		"MyEmpty.type":       "string",
+		"Orphan.type":        "string",
+		"Orphan.max":         "10",
+		"checkout.type":      "express",
+		"checkout.type.type": "string",
+		"MyValid":            "true",
+		"MyValid.type":       "boolean",
+	}
+
+	expected := []struct {
+		rule  LintRule
+		field string
+		fix   *Change
+	}{
+		{LintNonCanonicalBoolean, "MyBool", &Change{Op: OpChange, Field: "MyBool", Old: "1", New: "true"}},
+		{LintOrphanMetadata, "MyEmpty.type", &Change{Op: OpRemove, Field: "MyEmpty.type", Old: "string"}},
+		{LintNonCanonicalBoolean, "MyFlag", &Change{Op: OpChange, Field: "MyFlag", Old: "True", New: "true"}},
+		{LintUnknownType, "MyFlag.type", &Change{Op: OpChange, Field: "MyFlag.type", Old: "Boolean", New: "boolean"}},
+		{LintUnknownType, "MyInt.type", &Change{Op: OpChange, Field: "MyInt.type", Old: "int", New: "integer"}},
+		{LintConstraintViolation, "MyLimit", nil},
+		{LintInvalidValue, "MyNumber", nil},
+		{LintMissingType, "MyUntyped", &Change{Op: OpAdd, Field: "MyUntyped.type", New: "duration"}},
+		{LintUnknownType, "MyWeird.type", nil},
+		{LintOrphanMetadata, "Orphan.max", &Change{Op: OpRemove, Field: "Orphan.max", Old: "10"}},
+		{LintOrphanMetadata, "Orphan.type", &Change{Op: OpRemove, Field: "Orphan.type", Old: "string"}},
+	}
+
+	problems := Lint(raw)
+	if len(problems) != len(expected) {
+		t.Fatalf("Should have found %d problems, found %+v", len(expected), problems)
+	}
+	for i, e := range expected {
+		p := problems[i]
+		if p.Rule != e.rule || p.Field != e.field || (p.Fix == nil) != (e.fix == nil) || (p.Fix != nil && *p.Fix != *e.fix) {
+			t.Errorf("Should have found %s in %s with fix %+v, found %+v with fix %+v", e.rule, e.field, e.fix, p, p.Fix)
+		}
+	}
+
+	p := FixPlan("my-service", raw, problems)
+	fixed := map[string]string{}
+	for k, v := range raw {
+		fixed[k] = v
+	}
+	for _, c := range p.Changes {
+		if c.Op == OpRemove {
+			delete(fixed, c.Field)
+			continue
+		}
+		fixed[c.Field] = c.New
+	}
+
+	remaining := Lint(fixed)
+	if len(remaining) != 3 {
+		t.Errorf("Should have left only the problems that can not be fixed, left %+v", remaining)
+	}
+}
+
+func TestInferType(t *testing.T) {
+	tests := map[string]string{
+		"5":                    "integer",
+		"0.5":                  "number",
+		"false":                "boolean",
+		"2024-01-01T00:00:00Z": "datetime",
+		"1m30s":                "duration",
+		`["a"]`:                "list",
+		`{"a": 1}`:             "json",
+		"value":                "string",
+	}
+	for val, expected := range tests {
+		if actual := inferType(val); actual != expected {
+			t.Errorf("Should have inferred %s for %s, inferred %s", expected, val, actual)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/delivery-much/dm-go-ft/featuretoggle"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest represents the feature toggles of a service, declared in a versioned file
+type Manifest struct {
+	Service string
+	// the feature toggles, by key
+	Toggles map[string]Toggle
+}
+
+// Toggle represents a feature toggle with its metadata fields, as declared in a manifest
+type Toggle struct {
+	// the type of the feature toggle (the "<key>.type" field)
+	Type string `json:"type,omitempty"`
+	// the raw value of the feature toggle
+	Value string `json:"value"`
+	// the other metadata fields of the feature toggle, by name (like "max" for "<key>.max")
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// manifestFile represents the manifest file
+type manifestFile struct {
+	Service string                    `yaml:"service"`
+	Toggles map[string]manifestToggle `yaml:"toggles"`
+}
+
+// manifestToggle represents a feature toggle in the manifest file.
+// The values are kept as nodes, so the scalars are read exactly as written.
+type manifestToggle struct {
+	Type     string    `yaml:"type"`
+	Value    yaml.Node `yaml:"value"`
+	Enum     yaml.Node `yaml:"enum"`
+	Min      yaml.Node `yaml:"min"`
+	Max      yaml.Node `yaml:"max"`
+	Pattern  yaml.Node `yaml:"pattern"`
+	Schema   yaml.Node `yaml:"schema"`
+	Fallback yaml.Node `yaml:"fallback"`
+	Schedule yaml.Node `yaml:"schedule"`
+}
+
+/*
+ParseManifest parses a manifest in yaml (or json) format, like:
+
+	service: my-service
+	toggles:
+	  MyBool:
+	    type: boolean
+	    value: true
+	  MyLimit:
+	    type: integer
+	    value: 5
+	    max: 10
+	  MyConfig:
+	    type: json
+	    value: {"timeout": 30}
+
+The scalar values are read exactly as written, and the lists and maps (like json values, enums and schemas) are encoded as json.
+Every feature toggle is checked the same way the library would when loading it (see featuretoggle.Check),
+and an error is returned with the problems of every invalid feature toggle.
+*/
+func ParseManifest(data []byte) (*Manifest, error) {
+	var f manifestFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	err := dec.Decode(&f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	if f.Service == "" {
+		return nil, fmt.Errorf("invalid manifest: the service is required")
+	}
+
+	m := &Manifest{Service: f.Service, Toggles: make(map[string]Toggle, len(f.Toggles))}
+	for key, ft := range f.Toggles {
+		t, err := ft.toggle()
+		if err != nil {
+			return nil, fmt.Errorf("invalid manifest: feature toggle %s: %w", key, err)
+		}
+		m.Toggles[key] = t
+	}
+
+	err = m.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Validate checks every feature toggle of the manifest the same way the library would when loading them (see featuretoggle.Check).
+func (m *Manifest) Validate() error {
+	raw := m.Hash()
+	keys := featuretoggle.Keys(raw)
+
+	var errs []error
+	for key := range m.Toggles {
+		if !contains(keys, key) {
+			errs = append(errs, fmt.Errorf("feature toggle %s: the key is a metadata field of another feature toggle", key))
+			continue
+		}
+
+		err := featuretoggle.Check(raw, key)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Error() < errs[j].Error()
+	})
+	return fmt.Errorf("invalid manifest: %w", errors.Join(errs...))
+}
+
+// Hash returns the raw feature toggles of the manifest, in the format saved in redis.
+func (m *Manifest) Hash() map[string]string {
+	raw := map[string]string{}
+	for key, t := range m.Toggles {
+		raw[key] = t.Value
+		if t.Type != "" {
+			raw[key+".type"] = t.Type
+		}
+		for name, val := range t.Metadata {
+			raw[key+"."+name] = val
+		}
+	}
+	return raw
+}
+
+// toggle returns the feature toggle of the manifest file.
+func (ft manifestToggle) toggle() (Toggle, error) {
+	t := Toggle{Type: ft.Type}
+
+	val, ok, err := nodeString(&ft.Value)
+	if err != nil {
+		return t, fmt.Errorf("invalid value: %w", err)
+	}
+	if !ok {
+		return t, fmt.Errorf("the value is required")
+	}
+	t.Value = val
+
+	metadata := []struct {
+		name string
+		node *yaml.Node
+	}{
+		{"enum", &ft.Enum},
+		{"min", &ft.Min},
+		{"max", &ft.Max},
+		{"pattern", &ft.Pattern},
+		{"schema", &ft.Schema},
+		{"fallback", &ft.Fallback},
+		{"schedule", &ft.Schedule},
+	}
+	for _, md := range metadata {
+		val, ok, err := nodeString(md.node)
+		if err != nil {
+			return t, fmt.Errorf("invalid %s: %w", md.name, err)
+		}
+		if !ok {
+			continue
+		}
+
+		if t.Metadata == nil {
+			t.Metadata = map[string]string{}
+		}
+		t.Metadata[md.name] = val
+	}
+
+	return t, nil
+}
+
+// nodeString returns the node as a raw string: scalars exactly as written, and lists and maps encoded as json.
+// returns false if the node is missing or null.
+func nodeString(n *yaml.Node) (string, bool, error) {
+	switch {
+	case n.Kind == 0, n.Kind == yaml.ScalarNode && n.Tag == "!!null":
+		return "", false, nil
+	case n.Kind == yaml.ScalarNode:
+		return n.Value, true, nil
+	}
+
+	var v any
+	err := n.Decode(&v)
+	if err != nil {
+		return "", false, err
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	err = enc.Encode(v)
+	if err != nil {
+		return "", false, err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), true, nil
+}
+
+// contains checks if the values contain the value.
+func contains(values []string, val string) bool {
+	for _, v := range values {
+		if v == val {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package admin
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/delivery-much/dm-go-ft/featuretoggle"
+)
+
+func TestParseManifest(t *testing.T) {
+	t.Run("Should parse the yaml manifest into the redis format", func(t *testing.T) {
+		m, err := ParseManifest([]byte(`
+service: my-service
+toggles:
+  MyBool:
+    type: boolean
+    value: true
+  MyNumber:
+    type: number
+    value: 0.10
+  MyLimit:
+    type: integer
+    value: 5
+    min: 1
+    max: 10
+  MyColor:
+    type: string
+    value: blue
+    enum: [blue, red]
+  MyConfig:
+    type: json
+    value:
+      timeout: 30
+      url: "https://example.com/?a=1&b=2"
+    fallback: last_known_good
+  MyStart:
+    type: datetime
+    value: 2024-01-01T00:00:00Z
+`))
+		if err != nil {
+			t.Fatalf("Should have parsed the manifest, returned %v", err)
+		}
+
+		expected := map[string]string{
+			"MyBool":            "true",
+			"MyBool.type":       "boolean",
+			"MyNumber":          "0.10",
+			"MyNumber.type":     "number",
+			"MyLimit":           "5",
+			"MyLimit.type":      "integer",
+			"MyLimit.min":       "1",
+			"MyLimit.max":       "10",
+			"MyColor":           "blue",
+			"MyColor.type":      "string",
+			"MyColor.enum":      `["blue","red"]`,
+			"MyConfig":          `{"timeout":30,"url":"https://example.com/?a=1&b=2"}`,
+			"MyConfig.type":     "json",
+			"MyConfig.fallback": "last_known_good",
+			"MyStart":           "2024-01-01T00:00:00Z",
+			"MyStart.type":      "datetime",
+		}
+		raw := m.Hash()
+		if m.Service != "my-service" || len(raw) != len(expected) {
+			t.Fatalf("Should have parsed every field, parsed %v", raw)
+		}
+		for k, v := range expected {
+			if raw[k] != v {
+				t.Errorf("Should have parsed %s as %q, parsed %q", k, v, raw[k])
+			}
+		}
+	})
+	t.Run("Should parse the json manifest", func(t *testing.T) {
+		m, err := ParseManifest([]byte(`{"service": "my-service", "toggles": {"MyBool": {"type": "boolean", "value": false}}}`))
+		if err != nil || m.Toggles["MyBool"].Value != "false" {
+			t.Errorf("Should have parsed the json manifest, returned %+v, %v", m, err)
+		}
+	})
+	t.Run("Should parse the schedules", func(t *testing.T) {
+		m, err := ParseManifest([]byte(`
+service: my-service
+toggles:
+  Launch:
+    type: boolean
+    value: false
+    schedule:
+      timezone: America/Sao_Paulo
+      transitions:
+        - at: 2024-11-29T00:00:00
+          value: true
+        - at: 2024-12-02T00:00:00
+          value: false
+`))
+		if err != nil {
+			t.Fatalf("Should have parsed the manifest, returned %v", err)
+		}
+
+		expected := `{"timezone":"America/Sao_Paulo","transitions":[{"at":"2024-11-29T00:00:00","value":true},{"at":"2024-12-02T00:00:00","value":false}]}`
+		if schedule := m.Hash()["Launch.schedule"]; schedule != expected {
+			t.Errorf("Expected the schedule %s, parsed %s", expected, schedule)
+		}
+	})
+	t.Run("Should report every invalid feature toggle", func(t *testing.T) {
+		_, err := ParseManifest([]byte(`
+service: my-service
+toggles:
+  MyBool:
+    type: boolean
+    value: yes please
+  MyLimit:
+    type: integer
+    value: 20
+    max: 10
+  MyUntyped:
+    value: 1
+`))
+		if !errors.Is(err, featuretoggle.ErrParse) || !errors.Is(err, featuretoggle.ErrConstraintViolation) || !errors.Is(err, featuretoggle.ErrTypeMissing) {
+			t.Errorf("Should have reported every invalid feature toggle, returned %v", err)
+		}
+	})
+	t.Run("Should reject malformed manifests", func(t *testing.T) {
+		tests := map[string]string{
+			"unknown field":   "service: my-service\ntoggles:\n  MyBool:\n    type: boolean\n    value: true\n    maximum: 1\n",
+			"missing value":   "service: my-service\ntoggles:\n  MyBool:\n    type: boolean\n",
+			"missing service": "toggles:\n  MyBool:\n    type: boolean\n    value: true\n",
+			"metadata key":    "service: my-service\ntoggles:\n  MyBool:\n    type: boolean\n    value: true\n  MyBool.max:\n    type: integer\n    value: 1\n",
+		}
+		for name, manifest := range tests {
+			_, err := ParseManifest([]byte(manifest))
+			if err == nil || !strings.Contains(err.Error(), "invalid manifest") {
+				t.Errorf("Should have rejected the manifest with %s, returned %v", name, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package admin
+
+import (
+	"sort"
+
+	"github.com/delivery-much/dm-go-ft/featuretoggle"
+)
+
+// Operation represents the kind of change of a field
+type Operation string
+
+const (
+	// OpAdd means the field will be added
+	OpAdd Operation = "add"
+	// OpChange means the value of the field will be changed
+	OpChange Operation = "change"
+	// OpRemove means the field will be removed
+	OpRemove Operation = "remove"
+)
+
+// Change represents a change of a field of the service hash (a feature toggle key, or a metadata field like "<key>.type")
+type Change struct {
+	Op    Operation `json:"op"`
+	Field string    `json:"field"`
+	// the current value of the field, empty if it will be added
+	Old string `json:"old,omitempty"`
+	// the new value of the field, empty if it will be removed
+	New string `json:"new,omitempty"`
+}
+
+// Plan represents the changes needed to make the feature toggles of a service match the desired ones
+type Plan struct {
+	Service string `json:"service"`
+	// the changes, sorted by field
+	Changes []Change `json:"changes"`
+}
+
+// NewPlan returns the plan to change the current raw feature toggles of the service into the desired ones.
+// The version of the feature toggles (see featuretoggle.VersionField) is not planned, since it is incremented by Apply.
+func NewPlan(service string, current map[string]string, desired map[string]string) Plan {
+	p := Plan{Service: service, Changes: []Change{}}
+	for field, val := range desired {
+		if field == featuretoggle.VersionField {
+			continue
+		}
+
+		old, ok := current[field]
+		switch {
+		case !ok:
+			p.Changes = append(p.Changes, Change{Op: OpAdd, Field: field, New: val})
+		case old != val:
+			p.Changes = append(p.Changes, Change{Op: OpChange, Field: field, Old: old, New: val})
+		}
+	}
+	for field, old := range current {
+		if _, ok := desired[field]; !ok && field != featuretoggle.VersionField {
+			p.Changes = append(p.Changes, Change{Op: OpRemove, Field: field, Old: old})
+		}
+	}
+
+	sort.Slice(p.Changes, func(i, j int) bool {
+		return p.Changes[i].Field < p.Changes[j].Field
+	})
+	return p
+}
+
+// Empty checks if the plan has no changes
+func (p Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// Count returns the number of additions, changes and removals of the plan
+func (p Plan) Count() (add int, change int, remove int) {
+	for _, c := range p.Changes {
+		switch c.Op {
+		case OpAdd:
+			add++
+		case OpChange:
+			change++
+		case OpRemove:
+			remove++
+		}
+	}
+	return
+}
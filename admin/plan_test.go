@@ -0,0 +1,38 @@
+package admin
+
+import (
+	"testing"
+)
+
+func TestNewPlan(t *testing.T) {
+	p := NewPlan("my-service", map[string]string{
+		"MyBool":      "true",
+		"MyBool.type": "boolean",
+		"MyOld":       "value",
+	}, map[string]string{
+		"MyBool":      "false",
+		"MyBool.type": "boolean",
+		"MyNew":       "value",
+	})
+
+	expected := []Change{
+		{Op: OpChange, Field: "MyBool", Old: "true", New: "false"},
+		{Op: OpAdd, Field: "MyNew", New: "value"},
+		{Op: OpRemove, Field: "MyOld", Old: "value"},
+	}
+	if len(p.Changes) != len(expected) {
+		t.Fatalf("Should have planned %d changes, planned %+v", len(expected), p.Changes)
+	}
+	for i, c := range expected {
+		if p.Changes[i] != c {
+			t.Errorf("Should have planned %+v, planned %+v", c, p.Changes[i])
+		}
+	}
+
+	if add, change, remove := p.Count(); add != 1 || change != 1 || remove != 1 {
+		t.Errorf("Should have counted the changes, counted %d, %d and %d", add, change, remove)
+	}
+	if p.Empty() || !NewPlan("my-service", nil, nil).Empty() {
+		t.Errorf("Should have checked if the plans are empty")
+	}
+}
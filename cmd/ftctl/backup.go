@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/delivery-much/dm-go-ft/admin"
+	"github.com/delivery-much/dm-go-ft/featuretoggle"
+)
+
+// export writes a backup of the feature toggles of the service to the stdout, or to a file.
+// The backup keeps the secret values, since it is meant to be restored.
+func export(ctx context.Context, a *app, args []string) error {
+	var o options
+	fs := a.flags("export", &o)
+	out := fs.String("o", "", "the file to write the backup to, instead of the stdout")
+	st, _, err := a.parse(fs, &o, args, 0)
+	if err != nil {
+		return err
+	}
+
+	raw, err := st.getAll()
+	if err != nil {
+		return err
+	}
+
+	b := admin.NewBackup(o.service, raw)
+	if *out == "" {
+		return b.Write(a.stdout)
+	}
+
+	var buf bytes.Buffer
+	err = b.Write(&buf)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(*out, buf.Bytes(), 0o600)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.stderr, "exported %d feature toggles of %s to %s\n", len(featuretoggle.Keys(raw)), o.service, *out)
+	return nil
+}
+
+// restore replaces the feature toggles of the service with the ones of a backup, in a single transaction.
+// Restores to the service of the backup, unless another service is given.
+func restore(ctx context.Context, a *app, args []string) error {
+	var o options
+	fs := a.flags("import", &o)
+	dryRun := fs.Bool("dry-run", false, "prints the plan without applying it")
+	args, err := a.parseFlags(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	data, err := a.readFile(args[0])
+	if err != nil {
+		return err
+	}
+	b, err := admin.ReadBackup(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	if o.service == "" {
+		o.service = b.Service
+	}
+	st, err := a.open(o)
+	if err != nil {
+		return err
+	}
+
+	raw, err := st.getAll()
+	if err != nil {
+		return err
+	}
+
+	p := admin.NewPlan(o.service, raw, b.Toggles)
+	if !*dryRun {
+		err = st.apply(p)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = a.printPlan(o, p, raw, b.Toggles)
+	if err == nil && !o.json && !p.Empty() && !*dryRun {
+		fmt.Fprintf(a.stdout, "imported from %s (exported at %s)\n", b.Service, b.ExportedAt.Format("2006-01-02T15:04:05Z07:00"))
+	}
+	return err
+}
+
+// compare prints the differences of the feature toggles between two sources, key by key (see admin.Diff).
+// Each source is a backup file, or a service in redis as "redis://<host>:<port>/<db>/<service>".
+func compare(ctx context.Context, a *app, args []string) error {
+	var o options
+	args, err := a.parseFlags(a.flags("diff", &o), args, 2)
+	if err != nil {
+		return err
+	}
+
+	left, err := a.source(o, args[0])
+	if err != nil {
+		return err
+	}
+	right, err := a.source(o, args[1])
+	if err != nil {
+		return err
+	}
+
+	diffs := admin.Diff(left, right)
+	if !o.secrets {
+		for _, d := range diffs {
+			redactToggle(d.Left)
+			redactToggle(d.Right)
+		}
+	}
+
+	return a.print(o, diffs, func(w io.Writer) {
+		if len(diffs) == 0 {
+			fmt.Fprintln(w, "no differences")
+			return
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "KEY\tDIFFERENCE\t%s\t%s\n", args[0], args[1])
+		for _, d := range diffs {
+			kinds := make([]string, len(d.Kinds))
+			for i, k := range d.Kinds {
+				kinds[i] = strings.ReplaceAll(string(k), "_", " ")
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", d.Key, strings.Join(kinds, ", "), describe(d.Left), describe(d.Right))
+		}
+		tw.Flush()
+	})
+}
+
+// source returns the raw feature toggles of a backup file, or of a service in redis ("redis://<host>:<port>/<db>/<service>").
+func (a *app) source(o options, src string) (map[string]string, error) {
+	if !strings.HasPrefix(src, "redis://") {
+		data, err := a.readFile(src)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := admin.ReadBackup(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", src, err)
+		}
+		return b.Toggles, nil
+	}
+
+	u, err := url.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+
+	o.host, o.port, o.db = u.Hostname(), u.Port(), 0
+	if o.port == "" {
+		o.port = "6379"
+	}
+
+	path := strings.Split(strings.Trim(u.Path, "/"), "/")
+	switch len(path) {
+	case 1:
+		o.service = path[0]
+	case 2:
+		o.db, err = strconv.Atoi(path[0])
+		o.service = path[1]
+	}
+	if err != nil || o.service == "" {
+		return nil, fmt.Errorf("invalid source %s, expected redis://<host>:<port>/<db>/<service>", src)
+	}
+
+	st, err := a.connect(o)
+	if err != nil {
+		return nil, err
+	}
+	return st.getAll()
+}
+
+// redactToggle redacts the value of the feature toggle, and its metadata fields with values, if it is of type "secret".
+func redactToggle(t *admin.Toggle) {
+	if t == nil || t.Type != "secret" {
+		return
+	}
+
+	t.Value = redact(t.Value)
+	for name, val := range t.Metadata {
+		if featuretoggle.SecretMetadata(name) {
+			t.Metadata[name] = redact(val)
+		}
+	}
+}
+
+// describe returns the feature toggle as text, like `"5" (integer, max=10)`, or "-" if it is nil.
+func describe(t *admin.Toggle) string {
+	if t == nil {
+		return "-"
+	}
+
+	details := []string{t.Type}
+	if t.Type == "" {
+		details[0] = "no type"
+	}
+
+	var names []string
+	for name := range t.Metadata {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		details = append(details, fmt.Sprintf("%s=%s", name, t.Metadata[name]))
+	}
+
+	return fmt.Sprintf("%q (%s)", t.Value, strings.Join(details, ", "))
+}
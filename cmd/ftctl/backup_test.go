@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/delivery-much/dm-go-ft/admin"
+)
+
+func TestExportImport(t *testing.T) {
+	staging := newStore()
+	production := &fakeStore{hash: map[string]string{"MyOld": "value", "MyOld.type": "string"}}
+	stores := map[string]*fakeStore{"my-service": staging, "other-service": production}
+
+	file := filepath.Join(t.TempDir(), "backup.json")
+	code, _, stderr := runStores(stores, "", "export", "-service", "my-service", "-o", file)
+	if code != 0 {
+		t.Fatalf("Should have exported the feature toggles, exited with %d: %s", code, stderr)
+	}
+
+	data, _ := os.ReadFile(file)
+	var b admin.Backup
+	err := json.Unmarshal(data, &b)
+	if err != nil || b.Service != "my-service" || b.Toggles["MyToken"] != "s3cr3t" || b.Toggles["Broken"] != "True" {
+		t.Fatalf("Should have exported the feature toggles exactly as saved, exported %s", data)
+	}
+
+	code, stdout, stderr := runStores(stores, "", "import", "-service", "other-service", "-dry-run", file)
+	if code != 0 || !strings.Contains(stdout, "8 to add, 0 to change, 2 to remove") || production.applied != 0 {
+		t.Fatalf("Should have printed the plan without applying it, exited with %d: %s%s", code, stdout, stderr)
+	}
+
+	code, _, stderr = runStores(stores, "", "import", "-service", "other-service", file)
+	if code != 0 || production.applied != 1 {
+		t.Fatalf("Should have imported the feature toggles, exited with %d: %s", code, stderr)
+	}
+	if len(production.hash) != len(staging.hash) || production.hash["MyLimit.max"] != "10" {
+		t.Errorf("Should have replaced the feature toggles with the backup, found %v", production.hash)
+	}
+
+	code, _, stderr = runStores(stores, `{"version": 2, "service": "my-service", "toggles": {}}`, "import", "-")
+	if code != 1 || !strings.Contains(stderr, "unsupported version") {
+		t.Errorf("Should have rejected the unknown backup version, exited with %d: %s", code, stderr)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	staging := newStore()
+	production := newStore()
+	production.hash["MyBool"] = "false"
+	production.hash["MyLimit.type"] = "number"
+	production.hash["MyLimit.max"] = "20"
+	production.hash["MyToken"] = "other"
+	delete(production.hash, "Broken")
+	production.hash["MyNew"] = "value"
+	stores := map[string]*fakeStore{"my-service": staging, "prod-service": production}
+
+	code, stdout, stderr := runStores(stores, "", "diff", "-json", "redis://staging:6379/0/my-service", "redis://production/prod-service")
+	if code != 0 {
+		t.Fatalf("Should have compared the services, exited with %d: %s", code, stderr)
+	}
+
+	var diffs []admin.KeyDiff
+	err := json.Unmarshal([]byte(stdout), &diffs)
+	if err != nil {
+		t.Fatalf("Should have printed the differences as json, printed %s", stdout)
+	}
+
+	expected := map[string]string{
+		"Broken":  "only_left",
+		"MyBool":  "value",
+		"MyLimit": "type,metadata",
+		"MyNew":   "only_right",
+		"MyToken": "value",
+	}
+	if len(diffs) != len(expected) {
+		t.Fatalf("Should have found the differences, found %+v", diffs)
+	}
+	for _, d := range diffs {
+		kinds := make([]string, len(d.Kinds))
+		for i, k := range d.Kinds {
+			kinds[i] = string(k)
+		}
+		if strings.Join(kinds, ",") != expected[d.Key] {
+			t.Errorf("Should have found %s for %s, found %v", expected[d.Key], d.Key, kinds)
+		}
+		if d.Key == "MyToken" && (d.Left.Value != "[REDACTED]" || d.Right.Value != "[REDACTED]") {
+			t.Errorf("Should have redacted the secret values, found %+v %+v", d.Left, d.Right)
+		}
+	}
+
+	code, stdout, _ = runStores(stores, "", "diff", "redis://staging/my-service", "redis://staging/my-service")
+	if code != 0 || stdout != "no differences\n" {
+		t.Errorf("Should not have found differences, exited with %d: %s", code, stdout)
+	}
+
+	code, _, stderr = runStores(stores, "", "diff", "redis://staging/0/my-service/extra", "redis://staging/my-service")
+	if code != 1 || !strings.Contains(stderr, "invalid source") {
+		t.Errorf("Should have rejected the invalid source, exited with %d: %s", code, stderr)
+	}
+}
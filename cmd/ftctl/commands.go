@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/delivery-much/dm-go-ft/admin"
+	"github.com/delivery-much/dm-go-ft/featuretoggle"
+)
+
+// toggle represents a feature toggle, as printed by the commands
+type toggle struct {
+	Key      string            `json:"key"`
+	Type     string            `json:"type,omitempty"`
+	Value    string            `json:"value"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// change represents a change of a field of the service hash, as printed by watch
+type change struct {
+	Field string    `json:"field"`
+	Old   string    `json:"old"`
+	New   string    `json:"new"`
+	Time  time.Time `json:"time"`
+}
+
+// list lists the feature toggles of the service.
+func list(ctx context.Context, a *app, args []string) error {
+	var o options
+	st, _, err := a.parse(a.flags("list", &o), &o, args, 0)
+	if err != nil {
+		return err
+	}
+
+	raw, err := st.getAll()
+	if err != nil {
+		return err
+	}
+
+	toggles := []toggle{}
+	for _, key := range featuretoggle.Keys(raw) {
+		toggles = append(toggles, newToggle(raw, key, o.secrets))
+	}
+
+	return a.print(o, toggles, func(w io.Writer) {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "KEY\tTYPE\tVALUE\tSTATUS")
+		for _, t := range toggles {
+			status := "ok"
+			if t.Error != "" {
+				status = t.Error
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", t.Key, t.Type, t.Value, status)
+		}
+		tw.Flush()
+	})
+}
+
+// get prints the value of a feature toggle.
+func get(ctx context.Context, a *app, args []string) error {
+	var o options
+	st, args, err := a.parse(a.flags("get", &o), &o, args, 1)
+	if err != nil {
+		return err
+	}
+
+	raw, err := st.getAll()
+	if err != nil {
+		return err
+	}
+
+	key := args[0]
+	if _, ok := raw[key]; !ok {
+		return fmt.Errorf("feature toggle %s not found", key)
+	}
+
+	t := newToggle(raw, key, o.secrets)
+	return a.print(o, t, func(w io.Writer) {
+		fmt.Fprintln(w, t.Value)
+	})
+}
+
+// set validates and sets the value of a feature toggle, and its type.
+// The value is checked against the type and the constraints of the feature toggle before it is written,
+// and boolean values are written as "true" or "false".
+func set(ctx context.Context, a *app, args []string) error {
+	var o options
+	fs := a.flags("set", &o)
+	typ := fs.String("type", "", "the type of the feature toggle, required for new feature toggles ("+strings.Join(featuretoggle.Types, ", ")+")")
+	st, args, err := a.parse(fs, &o, args, 2)
+	if err != nil {
+		return err
+	}
+
+	raw, err := st.getAll()
+	if err != nil {
+		return err
+	}
+
+	key, val := args[0], args[1]
+	fields, err := setFields(raw, key, val, *typ)
+	if err != nil {
+		return err
+	}
+
+	next := copyHash(raw)
+	for field, v := range fields {
+		next[field] = v
+	}
+	err = st.apply(admin.NewPlan(o.service, raw, next))
+	if err != nil {
+		return err
+	}
+
+	t := newToggle(next, key, o.secrets)
+	return a.print(o, t, func(w io.Writer) {
+		fmt.Fprintf(w, "%s = %s (%s)\n", t.Key, t.Value, t.Type)
+	})
+}
+
+// setFields returns the fields to set the value of the key, validated against its type and constraints.
+// Uses the current type of the key if typ is empty.
+func setFields(raw map[string]string, key string, val string, typ string) (map[string]string, error) {
+	typeField := key + ".type"
+	if typ == "" {
+		typ = raw[typeField]
+	}
+	if typ == "" {
+		return nil, fmt.Errorf("the type of the new feature toggle %s is required, use -type", key)
+	}
+
+	if typ == "boolean" {
+		if b, err := strconv.ParseBool(val); err == nil {
+			val = strconv.FormatBool(b)
+		}
+	}
+
+	next := copyHash(raw)
+	next[key], next[typeField] = val, typ
+
+	if !contains(featuretoggle.Keys(next), key) {
+		return nil, fmt.Errorf("%s is a metadata field of another feature toggle", key)
+	}
+	err := featuretoggle.Check(next, key)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]string{key: val}
+	if raw[typeField] != typ {
+		fields[typeField] = typ
+	}
+	return fields, nil
+}
+
+// del deletes a feature toggle and its metadata fields.
+func del(ctx context.Context, a *app, args []string) error {
+	var o options
+	st, args, err := a.parse(a.flags("delete", &o), &o, args, 1)
+	if err != nil {
+		return err
+	}
+
+	raw, err := st.getAll()
+	if err != nil {
+		return err
+	}
+
+	key := args[0]
+	if _, ok := raw[key]; !ok {
+		return fmt.Errorf("feature toggle %s not found", key)
+	}
+
+	fields := append([]string{key}, featuretoggle.MetadataFields(raw, key)...)
+	next := copyHash(raw)
+	for _, field := range fields {
+		delete(next, field)
+	}
+	err = st.apply(admin.NewPlan(o.service, raw, next))
+	if err != nil {
+		return err
+	}
+
+	return a.print(o, map[string][]string{"deleted": fields}, func(w io.Writer) {
+		fmt.Fprintf(w, "deleted %s\n", strings.Join(fields, ", "))
+	})
+}
+
+// watch prints the changes of the feature toggles as they happen, until interrupted.
+// The json output has a change per line.
+func watch(ctx context.Context, a *app, args []string) error {
+	var o options
+	st, _, err := a.parse(a.flags("watch", &o), &o, args, 0)
+	if err != nil {
+		return err
+	}
+
+	prev, err := st.getAll()
+	if err != nil {
+		return err
+	}
+	if !o.json {
+		fmt.Fprintf(a.stdout, "watching %d feature toggles of %s\n", len(featuretoggle.Keys(prev)), o.service)
+	}
+
+	return st.watch(ctx, func() {
+		next, err := st.getAll()
+		if err != nil {
+			fmt.Fprintf(a.stderr, "ftctl watch: %s\n", err)
+			return
+		}
+
+		for _, c := range diff(prev, next, time.Now(), o.secrets) {
+			if o.json {
+				a.printLine(c)
+				continue
+			}
+			fmt.Fprintf(a.stdout, "%s %s: %q -> %q\n", c.Time.Format(time.RFC3339), c.Field, c.Old, c.New)
+		}
+		prev = next
+	})
+}
+
+// eval evaluates a feature toggle the same way the library does (see featuretoggle.EvaluateRaw).
+func eval(ctx context.Context, a *app, args []string) error {
+	var o options
+	st, args, err := a.parse(a.flags("eval", &o), &o, args, 1)
+	if err != nil {
+		return err
+	}
+
+	raw, err := st.getAll()
+	if err != nil {
+		return err
+	}
+
+	ev := featuretoggle.EvaluateRaw(raw, args[0])
+	res := struct {
+		Key    string `json:"key"`
+		Type   string `json:"type,omitempty"`
+		Value  any    `json:"value"`
+		Raw    string `json:"raw"`
+		Reason string `json:"reason"`
+		Error  string `json:"error,omitempty"`
+	}{Key: ev.Key, Type: ev.Type, Value: ev.Value, Raw: ev.Raw, Reason: ev.Reason}
+	if ev.Err != nil {
+		res.Error = ev.Err.Error()
+	}
+	if d, ok := ev.Value.(time.Duration); ok {
+		res.Value = d.String()
+	}
+	if ev.Type == "secret" && !o.secrets {
+		res.Value, res.Raw = featuretoggle.Redacted, featuretoggle.Redacted
+	}
+
+	err = a.print(o, res, func(w io.Writer) {
+		if res.Error != "" {
+			fmt.Fprintf(w, "%s: %s\n", res.Reason, res.Error)
+			return
+		}
+		fmt.Fprintf(w, "%v (%s, %s)\n", res.Value, res.Type, res.Reason)
+	})
+	if err == nil && ev.Err != nil {
+		return fmt.Errorf("the feature toggle can not be used")
+	}
+	return err
+}
+
+// newToggle returns the feature toggle of the key in the raw feature toggles, with its problem, if any.
+func newToggle(raw map[string]string, key string, secrets bool) toggle {
+	t := toggle{Key: key, Type: raw[key+".type"], Value: raw[key]}
+	for _, field := range featuretoggle.MetadataFields(raw, key) {
+		if field == key+".type" {
+			continue
+		}
+		if t.Metadata == nil {
+			t.Metadata = map[string]string{}
+		}
+		t.Metadata[strings.TrimPrefix(field, key+".")] = raw[field]
+	}
+
+	if err := featuretoggle.Check(raw, key); err != nil {
+		t.Error = strings.TrimPrefix(err.Error(), fmt.Sprintf("feature toggle %s: ", key))
+	}
+	if t.Type == "secret" && !secrets {
+		t.Value = featuretoggle.Redacted
+		for name, val := range t.Metadata {
+			if featuretoggle.SecretMetadata(name) {
+				t.Metadata[name] = redact(val)
+			}
+		}
+	}
+	return t
+}
+
+// diff returns the changes of the fields between the previous and the next raw feature toggles, sorted by field.
+// The values of the feature toggles of type "secret" are redacted, unless secrets is true.
+func diff(prev map[string]string, next map[string]string, now time.Time, secrets bool) []change {
+	var changes []change
+	for _, c := range redactChanges(admin.NewPlan("", prev, next).Changes, prev, next, secrets) {
+		changes = append(changes, change{Field: c.Field, Old: c.Old, New: c.New, Time: now})
+	}
+	return changes
+}
+
+// redactChanges redacts the values of the changes of the feature toggles of type "secret" (and of their metadata fields with values),
+// in the previous or in the next raw feature toggles, unless secrets is true.
+func redactChanges(changes []admin.Change, prev map[string]string, next map[string]string, secrets bool) []admin.Change {
+	if secrets {
+		return changes
+	}
+
+	redacted := make([]admin.Change, len(changes))
+	for i, c := range changes {
+		if featuretoggle.Secret(prev, c.Field) || featuretoggle.Secret(next, c.Field) {
+			c.Old, c.New = redact(c.Old), redact(c.New)
+		}
+		redacted[i] = c
+	}
+	return redacted
+}
+
+// copyHash returns a copy of the raw feature toggles.
+func copyHash(raw map[string]string) map[string]string {
+	next := make(map[string]string, len(raw))
+	for k, v := range raw {
+		next[k] = v
+	}
+	return next
+}
+
+// redact redacts the value, if it is not empty.
+func redact(val string) string {
+	if val == "" {
+		return ""
+	}
+	return featuretoggle.Redacted
+}
+
+// contains checks if the values contain the value.
+func contains(values []string, val string) bool {
+	for _, v := range values {
+		if v == val {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/delivery-much/dm-go-ft/admin"
+)
+
+// fakeStore is a store that keeps the service hash in memory
+type fakeStore struct {
+	hash    map[string]string
+	changes chan map[string]string
+	applied int
+	records []admin.Record
+}
+
+func (f *fakeStore) getAll() (map[string]string, error) {
+	raw := make(map[string]string, len(f.hash))
+	for k, v := range f.hash {
+		raw[k] = v
+	}
+	return raw, nil
+}
+
+func (f *fakeStore) apply(p admin.Plan) error {
+	f.applied++
+	for _, c := range p.Changes {
+		if c.Op == admin.OpRemove {
+			delete(f.hash, c.Field)
+			continue
+		}
+		f.hash[c.Field] = c.New
+	}
+	return nil
+}
+
+func (f *fakeStore) history(key string) ([]admin.Record, error) {
+	var records []admin.Record
+	for _, r := range f.records {
+		if r.Key == key {
+			records = append(records, r)
+		}
+	}
+	return records, nil
+}
+
+func (f *fakeStore) watch(ctx context.Context, changed func()) error {
+	for hash := range f.changes {
+		f.hash = hash
+		changed()
+	}
+	return nil
+}
+
+// runCommand runs the command against the store, returning the exit code and the outputs
+func runCommand(st *fakeStore, args ...string) (int, string, string) {
+	return runInput(st, "", args...)
+}
+
+// runInput runs the command against the store with the stdin, returning the exit code and the outputs
+func runInput(st *fakeStore, stdin string, args ...string) (int, string, string) {
+	args = append([]string{args[0], "-service", "my-service"}, args[1:]...)
+	return runStores(map[string]*fakeStore{"my-service": st}, stdin, args...)
+}
+
+// runStores runs the command against the stores of the services, returning the exit code and the outputs
+func runStores(stores map[string]*fakeStore, stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	a := &app{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr, connect: func(o options) (store, error) {
+		st, ok := stores[o.service]
+		if !ok {
+			return nil, fmt.Errorf("unknown service %s", o.service)
+		}
+		return st, nil
+	}}
+
+	code := a.run(context.Background(), args)
+	return code, stdout.String(), stderr.String()
+}
+
+func newStore() *fakeStore {
+	return &fakeStore{hash: map[string]string{
+		"MyBool":       "true",
+		"MyBool.type":  "boolean",
+		"MyLimit":      "5",
+		"MyLimit.type": "integer",
+		"MyLimit.max":  "10",
+		"MyToken":      "s3cr3t",
+		"MyToken.type": "secret",
+		"Broken":       "True",
+	}}
+}
+
+func TestList(t *testing.T) {
+	code, stdout, _ := runCommand(newStore(), "list", "-json")
+	if code != 0 {
+		t.Fatalf("Should have listed the feature toggles, exited with %d", code)
+	}
+
+	var toggles []toggle
+	err := json.Unmarshal([]byte(stdout), &toggles)
+	if err != nil {
+		t.Fatalf("Should have printed the feature toggles as json, printed %s", stdout)
+	}
+
+	if len(toggles) != 4 {
+		t.Fatalf("Should have listed the feature toggles without their metadata fields, listed %+v", toggles)
+	}
+	if toggles[0].Key != "Broken" || !strings.Contains(toggles[0].Error, "type was not found") {
+		t.Errorf("Should have reported the feature toggle without type, listed %+v", toggles[0])
+	}
+	if toggles[2].Key != "MyLimit" || toggles[2].Metadata["max"] != "10" || toggles[2].Error != "" {
+		t.Errorf("Should have listed the metadata fields, listed %+v", toggles[2])
+	}
+	if toggles[3].Value != "[REDACTED]" {
+		t.Errorf("Should have redacted the secret values, listed %+v", toggles[3])
+	}
+
+	_, stdout, _ = runCommand(newStore(), "list", "-secrets")
+	if !strings.Contains(stdout, "s3cr3t") || !strings.Contains(stdout, "KEY") {
+		t.Errorf("Should have printed the table with the secret values, printed %s", stdout)
+	}
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		code     int
+		expected map[string]string
+	}{
+		{"Should normalize the boolean values", []string{"MyBool", "False"}, 0, map[string]string{"MyBool": "false", "MyBool.type": "boolean"}},
+		{"Should set new feature toggles with the type", []string{"-type", "duration", "MyTimeout", "1m"}, 0, map[string]string{"MyTimeout": "1m", "MyTimeout.type": "duration"}},
+		{"Should change the type", []string{"-type", "string", "MyBool", "yes"}, 0, map[string]string{"MyBool": "yes", "MyBool.type": "string"}},
+		{"Should reject values that do not parse", []string{"MyBool", "yes"}, 1, map[string]string{"MyBool": "true"}},
+		{"Should reject values that violate the constraints", []string{"MyLimit", "20"}, 1, map[string]string{"MyLimit": "5"}},
+		{"Should reject unknown types", []string{"-type", "bool", "MyBool", "true"}, 1, map[string]string{"MyBool.type": "boolean"}},
+		{"Should require the type of new feature toggles", []string{"MyTimeout", "1m"}, 1, map[string]string{"MyTimeout": ""}},
+		{"Should reject metadata fields", []string{"-type", "integer", "MyLimit.max", "20"}, 1, map[string]string{"MyLimit.max": "10"}},
+		{"Should require the key and the value", []string{"MyBool"}, 2, map[string]string{"MyBool": "true"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := newStore()
+			code, _, stderr := runCommand(st, append([]string{"set"}, tt.args...)...)
+			if code != tt.code {
+				t.Errorf("Should have exited with %d, exited with %d: %s", tt.code, code, stderr)
+			}
+			for k, v := range tt.expected {
+				if st.hash[k] != v {
+					t.Errorf("Should have %s = %q, found %q", k, v, st.hash[k])
+				}
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	st := newStore()
+	code, _, _ := runCommand(st, "delete", "MyLimit")
+	if code != 0 {
+		t.Fatalf("Should have deleted the feature toggle, exited with %d", code)
+	}
+	for _, field := range []string{"MyLimit", "MyLimit.type", "MyLimit.max"} {
+		if _, ok := st.hash[field]; ok {
+			t.Errorf("Should have deleted the field %s", field)
+		}
+	}
+
+	code, _, _ = runCommand(st, "delete", "MyLimit")
+	if code != 1 {
+		t.Errorf("Should have failed to delete a missing feature toggle, exited with %d", code)
+	}
+}
+
+func TestEval(t *testing.T) {
+	code, stdout, _ := runCommand(newStore(), "eval", "-json", "MyLimit")
+	if code != 0 || !strings.Contains(stdout, `"value": 5`) || !strings.Contains(stdout, `"reason": "static"`) {
+		t.Errorf("Should have evaluated the feature toggle, exited with %d and printed %s", code, stdout)
+	}
+
+	st := newStore()
+	st.hash["MyLimit"] = "20"
+	code, stdout, _ = runCommand(st, "eval", "MyLimit")
+	if code != 1 || !strings.Contains(stdout, "error") {
+		t.Errorf("Should have reported the evaluation error, exited with %d and printed %s", code, stdout)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	st := newStore()
+	st.changes = make(chan map[string]string, 1)
+	next, _ := newStore().getAll()
+	next["MyBool"] = "false"
+	next["MyToken"] = "other"
+	delete(next, "Broken")
+	st.changes <- next
+	close(st.changes)
+
+	code, stdout, _ := runCommand(st, "watch", "-json")
+	if code != 0 {
+		t.Fatalf("Should have watched the changes, exited with %d", code)
+	}
+
+	var changes []change
+	dec := json.NewDecoder(strings.NewReader(stdout))
+	for dec.More() {
+		var c change
+		if err := dec.Decode(&c); err != nil {
+			t.Fatalf("Should have printed a json change per line, printed %s", stdout)
+		}
+		changes = append(changes, c)
+	}
+
+	expected := []change{
+		{Field: "Broken", Old: "True"},
+		{Field: "MyBool", Old: "true", New: "false"},
+		{Field: "MyToken", Old: "[REDACTED]", New: "[REDACTED]"},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("Should have printed the changes, printed %+v", changes)
+	}
+	for i, e := range expected {
+		c := changes[i]
+		if c.Field != e.Field || c.Old != e.Old || c.New != e.New || c.Time.IsZero() || c.Time.After(time.Now()) {
+			t.Errorf("Should have printed the change %+v, printed %+v", e, c)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	code, _, stderr := runCommand(newStore(), "unknown")
+	if code != 2 || !strings.Contains(stderr, "unknown command") {
+		t.Errorf("Should have rejected the unknown command, exited with %d: %s", code, stderr)
+	}
+	for _, cmd := range commands {
+		if !strings.Contains(stderr, cmd.usage) {
+			t.Errorf("Should have printed the usage of every command, missing %q in %s", cmd.usage, stderr)
+		}
+	}
+}
+
+func TestSecretMetadata(t *testing.T) {
+	newSecretStore := func() *fakeStore {
+		return &fakeStore{hash: map[string]string{
+			"MyToken":          "s3cr3t",
+			"MyToken.type":     "secret",
+			"MyToken.enum":     `["s3cr3t", "0th3r"]`,
+			"MyToken.schedule": `{"transitions": [{"at": "2100-01-01T00:00:00Z", "value": "0th3r"}]}`,
+		}}
+	}
+	leaks := func(out string) bool {
+		return strings.Contains(out, "s3cr3t") || strings.Contains(out, "0th3r") || strings.Contains(out, "n3w")
+	}
+
+	t.Run("get", func(t *testing.T) {
+		code, stdout, _ := runCommand(newSecretStore(), "get", "-json", "MyToken")
+		if code != 0 || leaks(stdout) || !strings.Contains(stdout, `"schedule": "[REDACTED]"`) {
+			t.Errorf("Should have redacted the metadata fields of the secret, exited with %d: %s", code, stdout)
+		}
+	})
+	t.Run("watch", func(t *testing.T) {
+		st := newSecretStore()
+		next, _ := st.getAll()
+		next["MyToken.enum"] = `["s3cr3t", "n3w"]`
+		next["MyToken.schedule"] = `{"transitions": [{"at": "2100-01-01T00:00:00Z", "value": "n3w"}]}`
+		st.changes = make(chan map[string]string, 1)
+		st.changes <- next
+		close(st.changes)
+
+		code, stdout, _ := runCommand(st, "watch", "-json")
+		if code != 0 || leaks(stdout) || strings.Count(stdout, "\n") != 2 {
+			t.Errorf("Should have redacted the changes of the metadata fields of the secret, exited with %d: %s", code, stdout)
+		}
+	})
+	t.Run("plan", func(t *testing.T) {
+		code, stdout, stderr := runInput(newSecretStore(), `
+service: my-service
+toggles:
+  MyToken:
+    type: secret
+    value: s3cr3t
+    enum: [s3cr3t, n3w]
+`, "plan", "-")
+		if code != 0 || leaks(stdout) || !strings.Contains(stdout, "MyToken.enum") {
+			t.Errorf("Should have redacted the metadata fields of the secret, exited with %d: %s%s", code, stdout, stderr)
+		}
+	})
+	t.Run("history", func(t *testing.T) {
+		st := newSecretStore()
+		st.records = []admin.Record{
+			{Change: admin.Change{Op: admin.OpChange, Field: "MyToken.schedule", Old: "{}", New: `{"transitions": [{"at": "2100-01-01T00:00:00Z", "value": "0th3r"}]}`}, Key: "MyToken", Type: "secret", Version: 1},
+			{Change: admin.Change{Op: admin.OpChange, Field: "MyToken.enum", Old: `["s3cr3t"]`, New: `["s3cr3t", "0th3r"]`}, Key: "MyToken", Type: "secret", Version: 2},
+		}
+
+		code, stdout, _ := runCommand(st, "history", "MyToken")
+		if code != 0 || leaks(stdout) || strings.Count(stdout, "[REDACTED]") != 4 {
+			t.Errorf("Should have redacted the metadata fields of the secret, exited with %d: %s", code, stdout)
+		}
+	})
+	t.Run("history of a former secret", func(t *testing.T) {
+		st := newSecretStore()
+		st.records = []admin.Record{
+			{Change: admin.Change{Op: admin.OpChange, Field: "MyToken", Old: "s3cr3t", New: "public"}, Key: "MyToken", Type: "string", OldType: "secret", Version: 1},
+			{Change: admin.Change{Op: admin.OpRemove, Field: "MyToken.enum", Old: `["s3cr3t", "0th3r"]`}, Key: "MyToken", Type: "string", OldType: "secret", Version: 1},
+		}
+
+		code, stdout, _ := runCommand(st, "history", "MyToken")
+		if code != 0 || leaks(stdout) || strings.Count(stdout, "[REDACTED]") != 3 {
+			t.Errorf("Should have redacted the values of the fields that were secret before the change, exited with %d: %s", code, stdout)
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/delivery-much/dm-go-ft/admin"
+	"github.com/delivery-much/dm-go-ft/featuretoggle"
+)
+
+// history prints the changes of a feature toggle and its metadata fields, oldest first (see admin.Client.History).
+func history(ctx context.Context, a *app, args []string) error {
+	var o options
+	st, args, err := a.parse(a.flags("history", &o), &o, args, 1)
+	if err != nil {
+		return err
+	}
+
+	records, err := st.history(args[0])
+	if err != nil {
+		return err
+	}
+	if !o.secrets {
+		for i, r := range records {
+			// secret before or after the change, so neither the old nor the new value is shown
+			secret := r.Type == "secret" || r.OldType == "secret"
+			if secret && (r.Field == r.Key || featuretoggle.SecretMetadata(strings.TrimPrefix(r.Field, r.Key+"."))) {
+				records[i].Old, records[i].New = redact(r.Old), redact(r.New)
+			}
+		}
+	}
+
+	return a.print(o, records, func(w io.Writer) {
+		if len(records) == 0 {
+			fmt.Fprintf(w, "no history of %s\n", args[0])
+			return
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tTIME\tACTOR\tFIELD\tOLD\tNEW\tREASON")
+		for _, r := range records {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				r.Version, r.Time.Format("2006-01-02T15:04:05Z07:00"), r.Actor, r.Field, recordValue(r.Old, r.Op != admin.OpAdd), recordValue(r.New, r.Op != admin.OpRemove), r.Reason)
+		}
+		tw.Flush()
+	})
+}
+
+// rollback rolls a feature toggle and its metadata fields back to their values at a version of the history (see admin.Revert).
+func rollback(ctx context.Context, a *app, args []string) error {
+	var o options
+	fs := a.flags("rollback", &o)
+	dryRun := fs.Bool("dry-run", false, "prints the plan without applying it")
+	st, args, err := a.parse(fs, &o, args, 2)
+	if err != nil {
+		return err
+	}
+
+	key := args[0]
+	version, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid version %q", args[1])
+	}
+
+	raw, err := st.getAll()
+	if err != nil {
+		return err
+	}
+	records, err := st.history(key)
+	if err != nil {
+		return err
+	}
+
+	desired := admin.Revert(raw, records, key, version)
+	p := admin.NewPlan(o.service, raw, desired)
+	if !*dryRun {
+		err = st.apply(p)
+		if err != nil {
+			return err
+		}
+	}
+
+	return a.printPlan(o, p, raw, desired)
+}
+
+// recordValue returns the value of a field in the history, or "-" if the field did not exist.
+func recordValue(val string, exists bool) string {
+	if !exists {
+		return "-"
+	}
+	return val
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/delivery-much/dm-go-ft/admin"
+)
+
+func TestHistory(t *testing.T) {
+	at := time.Date(2024, 5, 1, 3, 0, 0, 0, time.UTC)
+	newHistoryStore := func() *fakeStore {
+		return &fakeStore{
+			hash: map[string]string{
+				"CouponEngine":      "false",
+				"CouponEngine.type": "boolean",
+				"MyToken":           "new-token",
+				"MyToken.type":      "secret",
+			},
+			records: []admin.Record{
+				{Change: admin.Change{Op: admin.OpAdd, Field: "CouponEngine", New: "true"}, Key: "CouponEngine", Type: "boolean", Actor: "alice", Version: 1, Time: at},
+				{Change: admin.Change{Op: admin.OpAdd, Field: "CouponEngine.type", New: "boolean"}, Key: "CouponEngine", Type: "boolean", Actor: "alice", Version: 1, Time: at},
+				{Change: admin.Change{Op: admin.OpChange, Field: "CouponEngine", Old: "true", New: "false"}, Key: "CouponEngine", Type: "boolean", Actor: "bob", Reason: "too many coupons", Version: 2, Time: at},
+				{Change: admin.Change{Op: admin.OpChange, Field: "MyToken", Old: "old-token", New: "new-token"}, Key: "MyToken", Type: "secret", Actor: "bob", Version: 3, Time: at},
+			},
+		}
+	}
+
+	t.Run("Should print the history of the key", func(t *testing.T) {
+		code, stdout, stderr := runCommand(newHistoryStore(), "history", "CouponEngine")
+		if code != 0 {
+			t.Fatalf("Should have printed the history, exited with %d: %s", code, stderr)
+		}
+
+		lines := strings.Split(strings.TrimSpace(stdout), "\n")
+		if len(lines) != 4 {
+			t.Fatalf("Should have printed a line for each change, printed %s", stdout)
+		}
+		if fields := strings.Fields(lines[3]); strings.Join(fields, " ") != "2 2024-05-01T03:00:00Z bob CouponEngine true false too many coupons" {
+			t.Errorf("Should have printed who changed the key and why, printed %s", lines[3])
+		}
+		if !strings.Contains(lines[1], " - ") {
+			t.Errorf("Should have printed the missing old value of an addition, printed %s", lines[1])
+		}
+	})
+	t.Run("Should redact the secret values", func(t *testing.T) {
+		_, stdout, _ := runCommand(newHistoryStore(), "history", "-json", "MyToken")
+		if strings.Contains(stdout, "token") {
+			t.Errorf("Should have redacted the secret values, printed %s", stdout)
+		}
+
+		_, stdout, _ = runCommand(newHistoryStore(), "history", "-secrets", "MyToken")
+		if !strings.Contains(stdout, "old-token") {
+			t.Errorf("Should have printed the secret values with -secrets, printed %s", stdout)
+		}
+	})
+	t.Run("Should roll the key back to a version", func(t *testing.T) {
+		st := newHistoryStore()
+		code, stdout, stderr := runCommand(st, "rollback", "-dry-run", "CouponEngine", "1")
+		if code != 0 || st.applied != 0 || st.hash["CouponEngine"] != "false" {
+			t.Fatalf("Should have printed the plan without applying it, exited with %d: %s", code, stderr)
+		}
+		if !strings.Contains(stdout, "CouponEngine") {
+			t.Errorf("Should have printed the plan, printed %s", stdout)
+		}
+
+		code, _, stderr = runCommand(st, "rollback", "CouponEngine", "1")
+		if code != 0 || st.hash["CouponEngine"] != "true" {
+			t.Errorf("Should have rolled the key back, exited with %d: %s", code, stderr)
+		}
+
+		code, _, _ = runCommand(st, "rollback", "CouponEngine", "one")
+		if code != 1 {
+			t.Errorf("Should have refused an invalid version, exited with %d", code)
+		}
+	})
+}
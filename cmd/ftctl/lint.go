@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/delivery-much/dm-go-ft/admin"
+	"github.com/delivery-much/dm-go-ft/featuretoggle"
+)
+
+// lint reports the problems of the feature toggles of the service (see admin.Lint),
+// and fixes the ones that can be fixed automatically, in a single transaction, if requested.
+// Fails if any problem was not fixed.
+func lint(ctx context.Context, a *app, args []string) error {
+	var o options
+	fs := a.flags("lint", &o)
+	fix := fs.Bool("fix", false, "fixes the problems that can be fixed automatically")
+	st, _, err := a.parse(fs, &o, args, 0)
+	if err != nil {
+		return err
+	}
+
+	raw, err := st.getAll()
+	if err != nil {
+		return err
+	}
+
+	res := struct {
+		Problems []admin.LintProblem `json:"problems"`
+		Plan     *admin.Plan         `json:"plan,omitempty"`
+	}{Problems: admin.Lint(raw)}
+
+	unfixed := 0
+	for _, p := range res.Problems {
+		if !*fix || p.Fix == nil {
+			unfixed++
+		}
+	}
+
+	// planned before the redaction, so the fixes are applied with the actual values
+	p := admin.FixPlan(o.service, raw, res.Problems)
+	if *fix {
+		err = st.apply(p)
+		if err != nil {
+			return err
+		}
+		res.Plan = &p
+	}
+
+	if !o.secrets {
+		fixed := copyHash(raw)
+		for _, c := range p.Changes {
+			if c.Op == admin.OpRemove {
+				delete(fixed, c.Field)
+				continue
+			}
+			fixed[c.Field] = c.New
+		}
+		redactLint(res.Problems, raw, fixed)
+		if res.Plan != nil {
+			res.Plan.Changes = redactChanges(res.Plan.Changes, raw, fixed, false)
+		}
+	}
+
+	err = a.print(o, res, func(w io.Writer) {
+		if len(res.Problems) == 0 {
+			fmt.Fprintf(w, "no problems found in %s\n", o.service)
+			return
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "FIELD\tRULE\tPROBLEM\tFIX")
+		for _, p := range res.Problems {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", p.Field, p.Rule, p.Message, describeFix(p.Fix, *fix))
+		}
+		tw.Flush()
+	})
+	if err != nil {
+		return err
+	}
+
+	if unfixed > 0 {
+		return fmt.Errorf("%d problems found", unfixed)
+	}
+	return nil
+}
+
+// redactLint redacts the values of the feature toggles of type "secret" (and of their metadata fields with values)
+// from the messages and the fixes of the problems, secret in the raw feature toggles or after the fixes.
+func redactLint(problems []admin.LintProblem, raw map[string]string, fixed map[string]string) {
+	for i, p := range problems {
+		key := strings.TrimSuffix(p.Field, ".type")
+		if val := raw[key]; raw[key+".type"] == "secret" && val != "" {
+			problems[i].Message = strings.ReplaceAll(p.Message, val, featuretoggle.Redacted)
+		}
+
+		if p.Fix != nil && (featuretoggle.Secret(raw, p.Fix.Field) || featuretoggle.Secret(fixed, p.Fix.Field)) {
+			c := *p.Fix
+			c.Old, c.New = redact(c.Old), redact(c.New)
+			problems[i].Fix = &c
+		}
+	}
+}
+
+// describeFix returns the fix as text, like `"TRUE" -> "true"`.
+func describeFix(c *admin.Change, applied bool) string {
+	if c == nil {
+		return "-"
+	}
+
+	text := ""
+	switch c.Op {
+	case admin.OpRemove:
+		text = fmt.Sprintf("remove %s", c.Field)
+	default:
+		text = fmt.Sprintf("%s: %q -> %q", c.Field, c.Old, c.New)
+	}
+
+	if applied {
+		return text + " (fixed)"
+	}
+	return text
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/delivery-much/dm-go-ft/admin"
+)
+
+func TestLint(t *testing.T) {
+	newLintStore := func() *fakeStore {
+		return &fakeStore{hash: map[string]string{
+			"MyBool":          "TRUE",
+			"MyBool.type":     "bool",
+			"MyToken":         "s3cr3t",
+			"MyToken.type":    "secret",
+			"MyToken.pattern": "^[0-9]+$",
+			"Orphan.type":     "string",
+			"MyValid":         "true",
+			"MyValid.type":    "boolean",
+			"MyLimit":         "20",
+			"MyLimit.type":    "integer",
+			"MyLimit.max":     "10",
+			"MyUntyped":       `{"a": 1}`,
+		}}
+	}
+
+	t.Run("Should report the problems", func(t *testing.T) {
+		st := newLintStore()
+		code, stdout, stderr := runCommand(st, "lint", "-json")
+		if code != 1 || !strings.Contains(stderr, "6 problems found") {
+			t.Errorf("Should have failed with the problems, exited with %d: %s", code, stderr)
+		}
+
+		var res struct {
+			Problems []admin.LintProblem `json:"problems"`
+		}
+		err := json.Unmarshal([]byte(stdout), &res)
+		if err != nil || len(res.Problems) != 6 {
+			t.Fatalf("Should have printed the problems as json, printed %s", stdout)
+		}
+		if strings.Contains(stdout, "s3cr3t") {
+			t.Errorf("Should have redacted the secret values, printed %s", stdout)
+		}
+		if st.applied != 0 {
+			t.Errorf("Should not have fixed the problems without -fix")
+		}
+	})
+	t.Run("Should fix the problems that can be fixed", func(t *testing.T) {
+		st := newLintStore()
+		code, stdout, stderr := runCommand(st, "lint", "-fix")
+		if code != 1 || !strings.Contains(stderr, "2 problems found") {
+			t.Errorf("Should have failed with the problems that were not fixed, exited with %d: %s", code, stderr)
+		}
+		if !strings.Contains(stdout, `MyBool: "TRUE" -> "true" (fixed)`) {
+			t.Errorf("Should have printed the fixes, printed %s", stdout)
+		}
+
+		expected := map[string]string{"MyBool": "true", "MyBool.type": "boolean", "MyUntyped.type": "json", "MyLimit": "20"}
+		for k, v := range expected {
+			if st.hash[k] != v {
+				t.Errorf("Should have %s = %q, found %q", k, v, st.hash[k])
+			}
+		}
+		if _, ok := st.hash["Orphan.type"]; ok || st.applied != 1 {
+			t.Errorf("Should have removed the orphan metadata field at once, found %v", st.hash)
+		}
+	})
+	t.Run("Should redact the fixes of the metadata fields of the secrets", func(t *testing.T) {
+		newSecretStore := func() *fakeStore {
+			return &fakeStore{hash: map[string]string{
+				"MyToken":      "",
+				"MyToken.type": "secret",
+				"MyToken.enum": `["s3cr3t", "0th3r"]`,
+			}}
+		}
+
+		for _, args := range [][]string{{"lint", "-json"}, {"lint", "-json", "-fix"}, {"lint", "-fix"}} {
+			st := newSecretStore()
+			_, stdout, _ := runCommand(st, args...)
+			if strings.Contains(stdout, "s3cr3t") || strings.Contains(stdout, "0th3r") || !strings.Contains(stdout, "MyToken.enum") {
+				t.Errorf("Should have redacted the secret values with %v, printed %s", args, stdout)
+			}
+		}
+
+		st := newSecretStore()
+		runCommand(st, "lint", "-fix")
+		if _, ok := st.hash["MyToken.enum"]; ok {
+			t.Errorf("Should have applied the fixes with the actual values, found %v", st.hash)
+		}
+	})
+	t.Run("Should succeed without problems", func(t *testing.T) {
+		st := &fakeStore{hash: map[string]string{"MyValid": "true", "MyValid.type": "boolean"}}
+		code, stdout, _ := runCommand(st, "lint")
+		if code != 0 || !strings.Contains(stdout, "no problems found") {
+			t.Errorf("Should have succeeded, exited with %d: %s", code, stdout)
+		}
+	})
+}
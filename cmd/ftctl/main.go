@@ -0,0 +1,250 @@
+/*
+ftctl manages the feature toggles of a service in redis, in the same format read by the featuretoggle package:
+a hash named after the service, with a field for each feature toggle and its "<key>.type" metadata field.
+
+Usage:
+
+	ftctl <command> [flags] [args]
+
+The commands are:
+
+	list                      lists the feature toggles of the service
+	get <key>                 prints the value of a feature toggle
+	set [-type t] <key> <v>   validates and sets the value of a feature toggle
+	delete <key>              deletes a feature toggle and its metadata fields
+	watch                     prints the changes of the feature toggles as they happen
+	eval <key>                evaluates a feature toggle the same way the library does
+	plan <manifest>           prints the changes needed to make the service match the manifest
+	apply <manifest>          applies the changes needed to make the service match the manifest, at once
+	export [-o file]          writes a backup of the feature toggles of the service
+	import [-dry-run] <file>  replaces the feature toggles of the service with the ones of a backup
+	diff <source> <source>    prints the differences of the feature toggles between two sources
+	lint [-fix]               reports (and fixes) the problems of the feature toggles of the service
+	history <key>             prints the changes of a feature toggle, oldest first
+	rollback [-dry-run] <key> <version>
+	                          rolls a feature toggle back to its value at a version of its history
+
+Every command accepts the flags:
+
+	-host      the redis host (default $FT_REDIS_HOST or localhost)
+	-port      the redis port (default $FT_REDIS_PORT or 6379)
+	-db        the redis database (default $FT_REDIS_DB or 0)
+	-service   the service name (default $FT_SERVICE_NAME)
+	-json      prints the output as json
+	-secrets   prints the values of the feature toggles of type "secret", redacted by default
+	-stream    also appends every write to the change stream of the service (default $FT_CHANGE_STREAM)
+	-actor     who makes the writes, recorded in the history (default $FT_ACTOR or $USER)
+	-reason    why the writes are made, recorded in the history
+
+The flags must come before the arguments, like "ftctl set -service my-service -type boolean MyKey true".
+The plan and apply commands use the service of the manifest (see admin.ParseManifest), and "-" reads the manifest from the stdin.
+The import command uses the service of the backup if no service is given, so a backup can be imported into another service.
+Each source of the diff command is a backup file, or a service in redis as "redis://<host>:<port>/<db>/<service>".
+*/
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+)
+
+// errUsage means the command was called with invalid arguments, the usage was already printed
+var errUsage = errors.New("invalid usage")
+
+// app represents the command line application
+type app struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	// connects to redis, replaced in the tests
+	connect func(o options) (store, error)
+}
+
+// options represents the flags accepted by every command
+type options struct {
+	host    string
+	port    string
+	db      int
+	service string
+	json    bool
+	secrets bool
+	stream  bool
+	actor   string
+	reason  string
+}
+
+// command represents an ftctl command
+type command struct {
+	usage   string
+	summary string
+	run     func(ctx context.Context, a *app, args []string) error
+}
+
+// commands are the ftctl commands, by name
+var commands map[string]command
+
+// the commands are set on init, since they refer to the commands for their usage
+func init() {
+	commands = map[string]command{
+		"list":     {"list", "lists the feature toggles of the service", list},
+		"get":      {"get <key>", "prints the value of a feature toggle", get},
+		"set":      {"set [-type t] <key> <value>", "validates and sets the value of a feature toggle", set},
+		"delete":   {"delete <key>", "deletes a feature toggle and its metadata fields", del},
+		"watch":    {"watch", "prints the changes of the feature toggles as they happen", watch},
+		"eval":     {"eval <key>", "evaluates a feature toggle the same way the library does", eval},
+		"plan":     {"plan <manifest>", "prints the changes needed to make the service match the manifest", plan},
+		"apply":    {"apply <manifest>", "applies the changes needed to make the service match the manifest, at once", apply},
+		"export":   {"export [-o file]", "writes a backup of the feature toggles of the service", export},
+		"import":   {"import [-dry-run] <file>", "replaces the feature toggles of the service with the ones of a backup", restore},
+		"diff":     {"diff <source> <source>", "prints the differences of the feature toggles between two sources", compare},
+		"lint":     {"lint [-fix]", "reports (and fixes) the problems of the feature toggles of the service", lint},
+		"history":  {"history <key>", "prints the changes of a feature toggle, oldest first", history},
+		"rollback": {"rollback [-dry-run] <key> <version>", "rolls a feature toggle back to its value at a version of its history", rollback},
+	}
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	a := &app{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr, connect: connectRedis}
+	os.Exit(a.run(ctx, os.Args[1:]))
+}
+
+// run runs the command of the arguments, returning the exit code.
+func (a *app) run(ctx context.Context, args []string) int {
+	if len(args) == 0 {
+		a.usage()
+		return 2
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(a.stderr, "ftctl: unknown command %q\n", args[0])
+		a.usage()
+		return 2
+	}
+
+	err := cmd.run(ctx, a, args[1:])
+	switch {
+	case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+		return 2
+	case err != nil:
+		fmt.Fprintf(a.stderr, "ftctl %s: %s\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+// usage prints the commands.
+func (a *app) usage() {
+	fmt.Fprintln(a.stderr, "usage: ftctl <command> [flags] [args]")
+	fmt.Fprintln(a.stderr, "\ncommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(a.stderr, 0, 0, 2, ' ', 0)
+	for _, name := range names {
+		cmd := commands[name]
+		fmt.Fprintf(tw, "  %s\t%s\n", cmd.usage, cmd.summary)
+	}
+	tw.Flush()
+}
+
+// flags returns the flag set of the command, with the flags accepted by every command.
+func (a *app) flags(name string, o *options) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(a.stderr, "usage: ftctl %s\n\nflags:\n", commands[name].usage)
+		fs.PrintDefaults()
+	}
+
+	db, _ := strconv.Atoi(os.Getenv("FT_REDIS_DB"))
+	fs.StringVar(&o.host, "host", env("FT_REDIS_HOST", "localhost"), "the redis host")
+	fs.StringVar(&o.port, "port", env("FT_REDIS_PORT", "6379"), "the redis port")
+	fs.IntVar(&o.db, "db", db, "the redis database")
+	fs.StringVar(&o.service, "service", os.Getenv("FT_SERVICE_NAME"), "the service name")
+	fs.BoolVar(&o.json, "json", false, "prints the output as json")
+	fs.BoolVar(&o.secrets, "secrets", false, `prints the values of the feature toggles of type "secret"`)
+	fs.BoolVar(&o.stream, "stream", os.Getenv("FT_CHANGE_STREAM") == "true", "also appends every write to the change stream of the service")
+	fs.StringVar(&o.actor, "actor", env("FT_ACTOR", os.Getenv("USER")), "who makes the writes, recorded in the history")
+	fs.StringVar(&o.reason, "reason", "", "why the writes are made, recorded in the history")
+	return fs
+}
+
+// parse parses the flags of the command, checking the number of arguments.
+// returns the store of the service, and the arguments.
+func (a *app) parse(fs *flag.FlagSet, o *options, args []string, nargs int) (store, []string, error) {
+	args, err := a.parseFlags(fs, args, nargs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	st, err := a.open(*o)
+	if err != nil {
+		return nil, nil, err
+	}
+	return st, args, nil
+}
+
+// parseFlags parses the flags of the command, checking the number of arguments.
+func (a *app) parseFlags(fs *flag.FlagSet, args []string, nargs int) ([]string, error) {
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if fs.NArg() != nargs {
+		fs.Usage()
+		return nil, errUsage
+	}
+	return fs.Args(), nil
+}
+
+// open connects to the store of the service.
+func (a *app) open(o options) (store, error) {
+	if o.service == "" {
+		fmt.Fprintln(a.stderr, "ftctl: the service name is required, use -service or $FT_SERVICE_NAME")
+		return nil, errUsage
+	}
+
+	return a.connect(o)
+}
+
+// print prints the value as indented json if the json output was requested, or the text otherwise.
+func (a *app) print(o options, v any, text func(w io.Writer)) error {
+	if !o.json {
+		text(a.stdout)
+		return nil
+	}
+
+	enc := json.NewEncoder(a.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// printLine prints the value as json in a single line.
+func (a *app) printLine(v any) {
+	_ = json.NewEncoder(a.stdout).Encode(v)
+}
+
+// env returns the environment variable, or the default value if it is empty.
+func env(name string, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
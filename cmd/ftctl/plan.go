@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/delivery-much/dm-go-ft/admin"
+)
+
+// plan prints the changes needed to make the feature toggles of the service match the manifest.
+func plan(ctx context.Context, a *app, args []string) error {
+	var o options
+	st, m, err := a.manifest(a.flags("plan", &o), &o, args)
+	if err != nil {
+		return err
+	}
+
+	raw, err := st.getAll()
+	if err != nil {
+		return err
+	}
+
+	p := admin.NewPlan(m.Service, raw, m.Hash())
+	return a.printPlan(o, p, raw, m.Hash())
+}
+
+// apply applies the changes needed to make the feature toggles of the service match the manifest, in a single transaction.
+func apply(ctx context.Context, a *app, args []string) error {
+	var o options
+	st, m, err := a.manifest(a.flags("apply", &o), &o, args)
+	if err != nil {
+		return err
+	}
+
+	raw, err := st.getAll()
+	if err != nil {
+		return err
+	}
+
+	p := admin.NewPlan(m.Service, raw, m.Hash())
+	err = st.apply(p)
+	if err != nil {
+		return err
+	}
+
+	err = a.printPlan(o, p, raw, m.Hash())
+	if err == nil && !o.json && !p.Empty() {
+		fmt.Fprintln(a.stdout, "applied")
+	}
+	return err
+}
+
+// manifest parses the flags and the manifest of the command.
+// returns the store of the service of the manifest, and the manifest.
+func (a *app) manifest(fs *flag.FlagSet, o *options, args []string) (store, *admin.Manifest, error) {
+	args, err := a.parseFlags(fs, args, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := a.readFile(args[0])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m, err := admin.ParseManifest(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	o.service = m.Service
+	st, err := a.open(*o)
+	if err != nil {
+		return nil, nil, err
+	}
+	return st, m, nil
+}
+
+// printPlan prints the plan, with the values of the feature toggles of type "secret" redacted, unless requested.
+func (a *app) printPlan(o options, p admin.Plan, current map[string]string, desired map[string]string) error {
+	p.Changes = redactChanges(p.Changes, current, desired, o.secrets)
+
+	return a.print(o, p, func(w io.Writer) {
+		if p.Empty() {
+			fmt.Fprintf(w, "%s is up to date with the manifest\n", p.Service)
+			return
+		}
+
+		add, change, remove := p.Count()
+		fmt.Fprintf(w, "plan for %s: %d to add, %d to change, %d to remove\n", p.Service, add, change, remove)
+		for _, c := range p.Changes {
+			switch c.Op {
+			case admin.OpAdd:
+				fmt.Fprintf(w, "  + %s = %q\n", c.Field, c.New)
+			case admin.OpChange:
+				fmt.Fprintf(w, "  ~ %s: %q -> %q\n", c.Field, c.Old, c.New)
+			case admin.OpRemove:
+				fmt.Fprintf(w, "  - %s = %q\n", c.Field, c.Old)
+			}
+		}
+	})
+}
+
+// readFile reads the file of the path, or the stdin if the path is "-".
+func (a *app) readFile(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(a.stdin)
+	}
+	return os.ReadFile(path)
+}
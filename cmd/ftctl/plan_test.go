@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/delivery-much/dm-go-ft/admin"
+)
+
+const manifest = `
+service: my-service
+toggles:
+  MyBool:
+    type: boolean
+    value: false
+  MyLimit:
+    type: integer
+    value: 5
+    max: 10
+  MyToken:
+    type: secret
+    value: other
+  MyNew:
+    type: string
+    value: value
+`
+
+func TestPlan(t *testing.T) {
+	st := newStore()
+	code, stdout, stderr := runInput(st, manifest, "plan", "-")
+	if code != 0 {
+		t.Fatalf("Should have printed the plan, exited with %d: %s", code, stderr)
+	}
+
+	expected := []string{
+		"plan for my-service: 2 to add, 2 to change, 1 to remove",
+		`  - Broken = "True"`,
+		`  ~ MyBool: "true" -> "false"`,
+		`  + MyNew = "value"`,
+		`  ~ MyToken: "[REDACTED]" -> "[REDACTED]"`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(stdout, line+"\n") {
+			t.Errorf("Should have printed %q, printed\n%s", line, stdout)
+		}
+	}
+	if st.applied != 0 {
+		t.Errorf("Should not have applied the plan")
+	}
+
+	code, _, stderr = runInput(st, strings.Replace(manifest, "value: 5", "value: 20", 1), "plan", "-")
+	if code != 1 || !strings.Contains(stderr, "constraint") {
+		t.Errorf("Should have rejected the invalid manifest, exited with %d: %s", code, stderr)
+	}
+}
+
+func TestApplyManifest(t *testing.T) {
+	st := newStore()
+	code, stdout, stderr := runInput(st, manifest, "apply", "-json", "-")
+	if code != 0 {
+		t.Fatalf("Should have applied the plan, exited with %d: %s", code, stderr)
+	}
+
+	var p admin.Plan
+	err := json.Unmarshal([]byte(stdout), &p)
+	if err != nil || p.Service != "my-service" || len(p.Changes) != 5 {
+		t.Errorf("Should have printed the plan as json, printed %s", stdout)
+	}
+	if st.applied != 1 || st.hash["MyBool"] != "false" || st.hash["MyNew.type"] != "string" || st.hash["MyToken"] != "other" {
+		t.Errorf("Should have applied the plan at once, found %v", st.hash)
+	}
+	if _, ok := st.hash["Broken"]; ok {
+		t.Errorf("Should have removed the feature toggles missing from the manifest")
+	}
+
+	_, stdout, _ = runInput(st, manifest, "apply", "-")
+	if st.applied != 2 || !strings.Contains(stdout, "up to date") {
+		t.Errorf("Should not have changed the service that is up to date, printed %s", stdout)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/delivery-much/dm-go-ft/admin"
+	"github.com/go-redis/redis"
+)
+
+// store represents the redis hash of the service feature toggles
+type store interface {
+	// getAll returns every field of the service hash
+	getAll() (map[string]string, error)
+	// apply applies the plan to the service hash, in a single transaction
+	apply(p admin.Plan) error
+	// history returns the history of the feature toggle of the key, oldest first
+	history(key string) ([]admin.Record, error)
+	// watch calls changed whenever the service hash changes, until the context is done
+	watch(ctx context.Context, changed func()) error
+}
+
+// redisStore is the store of a service in redis
+type redisStore struct {
+	client  *redis.Client
+	admin   *admin.Client
+	db      int
+	service string
+}
+
+// connectRedis connects to the redis of the options.
+func connectRedis(o options) (store, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr: fmt.Sprintf("%s:%s", o.host, o.port),
+		DB:   o.db,
+	})
+
+	err := client.Ping().Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	ac := admin.New(client, o.service).WithAuthor(o.actor, o.reason)
+	if o.stream {
+		ac = ac.WithChangeStream(0)
+	}
+	return &redisStore{client: client, admin: ac, db: o.db, service: o.service}, nil
+}
+
+func (s *redisStore) getAll() (map[string]string, error) {
+	return s.admin.Toggles()
+}
+
+func (s *redisStore) apply(p admin.Plan) error {
+	return s.admin.Apply(p)
+}
+
+func (s *redisStore) history(key string) ([]admin.Record, error) {
+	return s.admin.History(key)
+}
+
+func (s *redisStore) watch(ctx context.Context, changed func()) error {
+	// the same notifications the library subscribes to, which may not be enabled yet
+	s.client.ConfigSet("notify-keyspace-events", "KEA")
+
+	sub := s.client.Subscribe(fmt.Sprintf("__keyspace@%d__:%s", s.db, s.service))
+	defer sub.Close()
+
+	_, err := sub.Receive()
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to the changes: %w", err)
+	}
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-ch:
+			if !ok {
+				return fmt.Errorf("the subscription was closed")
+			}
+			changed()
+		}
+	}
+}
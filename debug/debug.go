@@ -0,0 +1,237 @@
+// Package debug provides a mountable http.Handler that exposes the feature toggles in use by the instance,
+// to inspect what a given instance actually has in memory during incidents.
+package debug
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/delivery-much/dm-go-ft/featuretoggle"
+)
+
+// ErrUnauthorized means the request was not authorized to access the debug handler
+var ErrUnauthorized = errors.New("unauthorized")
+
+// Authorizer authorizes the requests to the debug handler
+type Authorizer interface {
+	// Authorize returns an error if the request is not authorized
+	Authorize(r *http.Request) error
+}
+
+// AuthorizerFunc adapts a function to the Authorizer interface
+type AuthorizerFunc func(r *http.Request) error
+
+// Authorize calls f(r)
+func (f AuthorizerFunc) Authorize(r *http.Request) error {
+	return f(r)
+}
+
+// BearerToken returns an Authorizer that accepts only the requests with the "Authorization: Bearer <token>" header.
+func BearerToken(token string) Authorizer {
+	return AuthorizerFunc(func(r *http.Request) error {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			return ErrUnauthorized
+		}
+		return nil
+	})
+}
+
+// Config represents the debug handler configuration
+type Config struct {
+	// Authorizer authorizes the requests, every request is denied if nil.
+	Authorizer Authorizer
+}
+
+// status represents the synchronization status, as json
+type status struct {
+	Initialized   bool      `json:"initialized"`
+	Connected     bool      `json:"connected"`
+	Subscribed    bool      `json:"subscribed"`
+	Stale         bool      `json:"stale"`
+	LastSync      time.Time `json:"last_sync"`
+	LastConfirmed time.Time `json:"last_confirmed"`
+	LastError     string    `json:"last_error,omitempty"`
+	Keys          int       `json:"keys"`
+	Hash          string    `json:"hash"`
+	Version       int64     `json:"version"`
+}
+
+// change represents a change of the feature toggles, as json
+type change struct {
+	Field string    `json:"field"`
+	Old   string    `json:"old"`
+	New   string    `json:"new"`
+	Time  time.Time `json:"time"`
+}
+
+// snapshotResponse represents the response with the feature toggles in use
+type snapshotResponse struct {
+	Status   status            `json:"status"`
+	Toggles  map[string]string `json:"toggles"`
+	Changes  []change          `json:"changes"`
+	Redacted []string          `json:"redacted,omitempty"`
+}
+
+// evaluationResponse represents the response with the evaluation of a feature toggle
+type evaluationResponse struct {
+	Key    string `json:"key"`
+	Type   string `json:"type,omitempty"`
+	Value  any    `json:"value"`
+	Raw    string `json:"raw"`
+	Reason string `json:"reason"`
+	Error  string `json:"error,omitempty"`
+}
+
+/*
+Handler returns a handler that exposes the feature toggles in use by the instance, as json.
+It can be mounted in any path, like "/debug/featuretoggles".
+
+GET requests without parameters return the feature toggles in use, the synchronization status
+(see featuretoggle.Status) and the recent changes (see featuretoggle.RecentChanges).
+
+GET requests with the "key" parameter return the evaluation of the key (see featuretoggle.Evaluate).
+The context of the evaluation may override feature toggles with "override" parameters, like "override=MyKey=true"
+(see featuretoggle.WithOverrides).
+
+The values of the feature toggles of type "secret" are redacted, with their metadata fields that hold values (see featuretoggle.Secret).
+*/
+func Handler(c Config) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+
+		if c.Authorizer == nil {
+			writeError(w, http.StatusForbidden, ErrUnauthorized)
+			return
+		}
+		if err := c.Authorizer.Authorize(r); err != nil {
+			writeError(w, http.StatusForbidden, err)
+			return
+		}
+
+		key := r.URL.Query().Get("key")
+		if key == "" {
+			write(w, http.StatusOK, snapshot())
+			return
+		}
+
+		ctx, err := withOverrides(r.Context(), r.URL.Query()["override"])
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		write(w, http.StatusOK, evaluate(ctx, key))
+	})
+}
+
+// snapshot returns the feature toggles in use, with the secret values redacted.
+func snapshot() snapshotResponse {
+	st := featuretoggle.Status()
+	res := snapshotResponse{
+		Status: status{
+			Initialized:   st.Initialized,
+			Connected:     st.Connected,
+			Subscribed:    st.Subscribed,
+			Stale:         st.Stale,
+			LastSync:      st.LastSync,
+			LastConfirmed: st.LastConfirmed,
+			Keys:          st.Keys,
+			Hash:          st.Hash,
+			Version:       st.Version,
+		},
+		Toggles: map[string]string{},
+		Changes: []change{},
+	}
+	if st.LastError != nil {
+		res.Status.LastError = st.LastError.Error()
+	}
+
+	// a single snapshot, so the redacted fields are decided with the same feature toggles that are returned
+	raw := featuretoggle.Snapshot()
+	for field, val := range raw {
+		res.Toggles[field] = val
+		if featuretoggle.Secret(raw, field) {
+			res.Toggles[field] = featuretoggle.Redacted
+			res.Redacted = append(res.Redacted, field)
+		}
+	}
+	sort.Strings(res.Redacted)
+
+	// the recent changes are recorded with the secret values already redacted
+	for _, c := range featuretoggle.RecentChanges() {
+		res.Changes = append(res.Changes, change{c.Field, c.Old, c.New, c.Time})
+	}
+
+	return res
+}
+
+// evaluate returns the evaluation of the key, with the secret values redacted.
+func evaluate(ctx context.Context, key string) evaluationResponse {
+	ev := featuretoggle.Evaluate(ctx, key)
+	res := evaluationResponse{
+		Key:    ev.Key,
+		Type:   ev.Type,
+		Value:  ev.Value,
+		Raw:    ev.Raw,
+		Reason: ev.Reason,
+	}
+	if ev.Err != nil {
+		res.Error = ev.Err.Error()
+	}
+	if d, ok := ev.Value.(time.Duration); ok {
+		res.Value = d.String()
+	}
+
+	if ev.Type == "secret" {
+		res.Value, res.Raw = redact(ev.Raw), redact(ev.Raw)
+	}
+	return res
+}
+
+// withOverrides returns a copy of the context with the overrides of the "override" parameters ("<key>=<value>").
+func withOverrides(ctx context.Context, params []string) (context.Context, error) {
+	if len(params) == 0 {
+		return ctx, nil
+	}
+
+	values := map[string]string{}
+	for _, p := range params {
+		key, val, ok := strings.Cut(p, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid override %q, expected <key>=<value>", p)
+		}
+		values[key] = val
+	}
+
+	return featuretoggle.WithOverrides(ctx, values), nil
+}
+
+// redact redacts the value, if it is not empty.
+func redact(val string) string {
+	if val == "" {
+		return ""
+	}
+	return featuretoggle.Redacted
+}
+
+// write writes the response as json.
+func write(w http.ResponseWriter, code int, res any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// writeError writes the error as json.
+func writeError(w http.ResponseWriter, code int, err error) {
+	write(w, code, map[string]string{"error": err.Error()})
+}
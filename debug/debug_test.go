@@ -0,0 +1,169 @@
+package debug
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/delivery-much/dm-go-ft/featuretoggle"
+)
+
+// serve calls the handler with the request, returning the status code and decoding the body into res
+func serve(t *testing.T, h http.Handler, r *http.Request, res any) int {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+
+	err := json.Unmarshal(rec.Body.Bytes(), res)
+	if err != nil {
+		t.Fatalf("Should have responded with json, responded %s", rec.Body.String())
+	}
+	return rec.Code
+}
+
+func TestHandler(t *testing.T) {
+	toggles := map[string]string{
+		"MyBool":        "true",
+		"MyBool.type":   "boolean",
+		"MyToken":       "s3cr3t",
+		"MyToken.type":  "secret",
+		"MyLimit":       "5",
+		"MyLimit.type":  "integer",
+		"MyLimit.max":   "10",
+		"MyString":      "value",
+		"MyString.type": "string",
+	}
+	allowAll := AuthorizerFunc(func(r *http.Request) error { return nil })
+
+	t.Run("Should deny the requests that are not authorized", func(t *testing.T) {
+		featuretoggle.Mock(toggles)
+		defer featuretoggle.Reset()
+
+		var res map[string]string
+		code := serve(t, Handler(Config{}), httptest.NewRequest(http.MethodGet, "/", nil), &res)
+		if code != http.StatusForbidden {
+			t.Errorf("Should have denied the request without authorizer, responded %v %v", code, res)
+		}
+
+		h := Handler(Config{Authorizer: BearerToken("my-token")})
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Authorization", "Bearer other-token")
+		code = serve(t, h, r, &res)
+		if code != http.StatusForbidden || res["error"] != ErrUnauthorized.Error() {
+			t.Errorf("Should have denied the request with an invalid token, responded %v %v", code, res)
+		}
+
+		r.Header.Set("Authorization", "Bearer my-token")
+		code = serve(t, h, r, &snapshotResponse{})
+		if code != http.StatusOK {
+			t.Errorf("Should have accepted the request with the token, responded %v", code)
+		}
+	})
+	t.Run("Should return the feature toggles in use with the secrets redacted", func(t *testing.T) {
+		featuretoggle.Mock(map[string]string{"MyToken": "old", "MyToken.type": "secret"})
+		featuretoggle.Mock(toggles)
+		defer featuretoggle.Reset()
+
+		var res snapshotResponse
+		code := serve(t, Handler(Config{Authorizer: allowAll}), httptest.NewRequest(http.MethodGet, "/", nil), &res)
+		if code != http.StatusOK {
+			t.Fatalf("Should have returned the feature toggles, responded %v", code)
+		}
+
+		if !res.Status.Initialized || res.Status.Keys != 4 || res.Status.Hash == "" {
+			t.Errorf("Should have returned the status, returned %+v", res.Status)
+		}
+		if res.Toggles["MyBool"] != "true" || res.Toggles["MyToken"] != featuretoggle.Redacted || res.Toggles["MyToken.type"] != "secret" {
+			t.Errorf("Should have returned the toggles with the secrets redacted, returned %v", res.Toggles)
+		}
+		if len(res.Redacted) != 1 || res.Redacted[0] != "MyToken" {
+			t.Errorf("Should have returned the redacted keys, returned %v", res.Redacted)
+		}
+		if len(res.Changes) == 0 {
+			t.Fatalf("Should have returned the recent changes")
+		}
+		if c := res.Changes[len(res.Changes)-1]; c.Field != "MyToken" || c.Old != featuretoggle.Redacted || c.New != featuretoggle.Redacted {
+			t.Errorf("Should have redacted the secret changes, returned %+v", c)
+		}
+	})
+	t.Run("Should redact the metadata fields with the values of the secrets", func(t *testing.T) {
+		featuretoggle.Mock(map[string]string{
+			"MyToken":          "s3cr3t",
+			"MyToken.type":     "secret",
+			"MyToken.enum":     `["s3cr3t", "0th3r"]`,
+			"MyToken.schedule": `{"transitions": [{"at": "2100-01-01T00:00:00Z", "value": "0th3r"}]}`,
+		})
+		defer featuretoggle.Reset()
+
+		rec := httptest.NewRecorder()
+		Handler(Config{Authorizer: allowAll}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if body := rec.Body.String(); strings.Contains(body, "s3cr3t") || strings.Contains(body, "0th3r") {
+			t.Errorf("Should have redacted the secret values, responded %s", body)
+		}
+
+		var res snapshotResponse
+		serve(t, Handler(Config{Authorizer: allowAll}), httptest.NewRequest(http.MethodGet, "/", nil), &res)
+		expected := []string{"MyToken", "MyToken.enum", "MyToken.schedule"}
+		if !reflect.DeepEqual(res.Redacted, expected) {
+			t.Errorf("Expected the redacted fields %v, returned %v", expected, res.Redacted)
+		}
+	})
+	t.Run("Should redact the changes of the secrets that were removed or changed type", func(t *testing.T) {
+		featuretoggle.Mock(map[string]string{"MyToken": "s3cr3t", "MyToken.type": "secret", "Other": "0th3r", "Other.type": "secret"})
+		featuretoggle.Mock(map[string]string{"Other": "public", "Other.type": "string"})
+		featuretoggle.Mock(map[string]string{})
+		defer featuretoggle.Reset()
+
+		rec := httptest.NewRecorder()
+		Handler(Config{Authorizer: allowAll}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if body := rec.Body.String(); strings.Contains(body, "s3cr3t") || strings.Contains(body, "0th3r") {
+			t.Errorf("Should have redacted the changes of the former secrets, responded %s", body)
+		}
+	})
+	t.Run("Should evaluate the key with the overrides", func(t *testing.T) {
+		featuretoggle.Mock(toggles)
+		defer featuretoggle.Reset()
+
+		h := Handler(Config{Authorizer: allowAll})
+		tests := []struct {
+			url      string
+			expected evaluationResponse
+		}{
+			{"/?key=MyBool", evaluationResponse{Key: "MyBool", Type: "boolean", Value: true, Raw: "true", Reason: featuretoggle.ReasonStatic}},
+			{"/?key=MyBool&override=MyBool=false", evaluationResponse{Key: "MyBool", Type: "boolean", Value: false, Raw: "false", Reason: featuretoggle.ReasonOverride}},
+			{"/?key=MyLimit", evaluationResponse{Key: "MyLimit", Type: "integer", Value: float64(5), Raw: "5", Reason: featuretoggle.ReasonStatic}},
+			{"/?key=MyToken", evaluationResponse{Key: "MyToken", Type: "secret", Value: featuretoggle.Redacted, Raw: featuretoggle.Redacted, Reason: featuretoggle.ReasonStatic}},
+		}
+		for _, tt := range tests {
+			var res evaluationResponse
+			code := serve(t, h, httptest.NewRequest(http.MethodGet, tt.url, nil), &res)
+			if code != http.StatusOK || res != tt.expected {
+				t.Errorf("Should have evaluated %s as %+v, responded %v %+v", tt.url, tt.expected, code, res)
+			}
+		}
+
+		var res evaluationResponse
+		serve(t, h, httptest.NewRequest(http.MethodGet, "/?key=MyLimit&override=MyLimit=five", nil), &res)
+		if res.Reason != featuretoggle.ReasonError || res.Error == "" || res.Value != nil {
+			t.Errorf("Should have returned the evaluation error, returned %+v", res)
+		}
+
+		var errRes map[string]string
+		code := serve(t, h, httptest.NewRequest(http.MethodGet, "/?key=MyBool&override=MyBool", nil), &errRes)
+		if code != http.StatusBadRequest {
+			t.Errorf("Should have rejected the invalid override, responded %v %v", code, errRes)
+		}
+	})
+}
+
+func TestBearerToken(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if err := BearerToken("").Authorize(r); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Should have denied every request with an empty token, returned %v", err)
+	}
+}
@@ -0,0 +1,370 @@
+package featuretoggle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// the struct tag used to bind struct fields to feature toggle keys
+const bindTag = "ft"
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+
+	// the refresh functions of every binding not unbound, called after each cache rebuild
+	bindings      []binding
+	bindingsMu    sync.Mutex
+	lastBindingID int
+)
+
+// binding represents the refresh function of a Binding, with the id used to unbind it
+type binding struct {
+	id      int
+	refresh func()
+}
+
+// Binding holds a copy of a struct whose fields are bound to feature toggles through `ft` struct tags.
+// The copy is rebuilt and atomically swapped every time the feature toggle cache is rebuilt.
+type Binding[T any] struct {
+	val  atomic.Pointer[T]
+	base T
+	plan []fieldBinding
+}
+
+// Load returns the latest bound copy of the struct.
+// The returned value is shared between callers and must be treated as read only.
+func (b *Binding[T]) Load() *T {
+	return b.val.Load()
+}
+
+// refresh populates a fresh copy of the base struct with the current feature toggles, and swaps it in.
+func (b *Binding[T]) refresh() {
+	c := b.base
+	populate(reflect.ValueOf(&c).Elem(), b.plan)
+	b.val.Store(&c)
+}
+
+/*
+Bind populates the fields of the struct pointed by target with the feature toggles declared in their `ft` struct tags.
+
+The tag holds the feature toggle key, optionally followed by a default value:
+
+	type Config struct {
+		MaxItems int           `ft:"checkout.max_items,default=10"`
+		Timeout  time.Duration `ft:"checkout.timeout,default=2s"`
+		Payment  struct {
+			Gateway string `ft:"gateway,default=stripe"`
+		} `ft:"payment"`
+	}
+
+Nested structs are bound recursively, and their tag (if any) is used as a prefix for the keys of their fields.
+Every field must have a matching `.type` field:
+
+- booleans: "boolean";
+
+- integers and floats: "integer" or "number";
+
+- strings: "string" or "secret";
+
+- durations: "duration", or "string" (ex.: "1m30s");
+
+- time.Time: "datetime" (RFC3339);
+
+- slices: "list", "json", or "string" (decoded as json);
+
+- maps: "json", or "string" (decoded as json).
+
+The default value is used if the feature toggle is not found, is empty, or is invalid.
+If no default value is specified, the field keeps the value it had when Bind was called.
+
+Bind returns a Binding that holds a copy of the populated struct, refreshed every time the feature toggles are updated,
+and a function that stops refreshing it. The target itself is populated only once.
+
+returns an error if target is not a pointer to a struct, if a field has an unsupported type,
+or if a default value is not valid for its field.
+*/
+func Bind[T any](target *T) (b *Binding[T], unbind func(), err error) {
+	if target == nil {
+		return nil, nil, fmt.Errorf("Failed to bind feature toggles, the target is nil")
+	}
+
+	v := reflect.ValueOf(target).Elem()
+	if v.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("Failed to bind feature toggles, the target must be a pointer to a struct, got %T", target)
+	}
+
+	plan, err := buildPlan(v.Type(), nil, "")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	b = &Binding[T]{
+		base: *target,
+		plan: plan,
+	}
+	populate(v, plan)
+
+	// added and refreshed under the lock, so a cache rebuild either is seen by the first refresh,
+	// or refreshes the binding after it
+	bindingsMu.Lock()
+	defer bindingsMu.Unlock()
+
+	lastBindingID++
+	id := lastBindingID
+	bindings = append(bindings, binding{id, b.refresh})
+	b.refresh()
+
+	return b, func() {
+		bindingsMu.Lock()
+		defer bindingsMu.Unlock()
+
+		for i, bd := range bindings {
+			if bd.id == id {
+				bindings = append(bindings[:i:i], bindings[i+1:]...)
+				return
+			}
+		}
+	}, nil
+}
+
+// refreshBindings refreshes every binding not unbound with the current feature toggles.
+func refreshBindings() {
+	bindingsMu.Lock()
+	defer bindingsMu.Unlock()
+
+	for _, bd := range bindings {
+		bd.refresh()
+	}
+}
+
+// resetBindings unbinds every binding.
+func resetBindings() {
+	bindingsMu.Lock()
+	defer bindingsMu.Unlock()
+
+	bindings = nil
+}
+
+// fieldBinding represents a struct field bound to a feature toggle key
+type fieldBinding struct {
+	index []int
+	key   string
+	// the feature toggle types accepted for the field
+	types      []string
+	defaultVal reflect.Value
+}
+
+// accepts checks if the feature toggle type t is accepted for the field
+func (fb fieldBinding) accepts(t string) bool {
+	for _, accepted := range fb.types {
+		if accepted == t {
+			return true
+		}
+	}
+	return false
+}
+
+// buildPlan walks the struct type t, and returns the bindings for every tagged field,
+// including the ones of nested structs.
+func buildPlan(t reflect.Type, index []int, prefix string) (plan []fieldBinding, err error) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, tagged := f.Tag.Lookup(bindTag)
+		if !f.IsExported() || tag == "-" {
+			continue
+		}
+
+		key, def, hasDef := parseTag(tag)
+		fieldIndex := append(append([]int{}, index...), i)
+
+		if f.Type.Kind() == reflect.Struct && f.Type != timeType {
+			nestedPrefix := prefix
+			if key != "" {
+				nestedPrefix = prefix + key + "."
+			}
+
+			nested, err := buildPlan(f.Type, fieldIndex, nestedPrefix)
+			if err != nil {
+				return nil, err
+			}
+			plan = append(plan, nested...)
+			continue
+		}
+
+		if !tagged || key == "" {
+			continue
+		}
+
+		types, ok := bindTypes(f.Type)
+		if !ok {
+			return nil, fmt.Errorf("Failed to bind feature toggle %s, the field %s has an unsupported type %s", prefix+key, f.Name, f.Type)
+		}
+
+		fb := fieldBinding{
+			index: fieldIndex,
+			key:   prefix + key,
+			types: types,
+		}
+		if hasDef {
+			dv := reflect.New(f.Type).Elem()
+			err := setValue(dv, def)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to bind feature toggle %s, the default value is invalid: %s", fb.key, err.Error())
+			}
+			fb.defaultVal = dv
+		}
+
+		plan = append(plan, fb)
+	}
+
+	return
+}
+
+// parseTag splits a `ft` tag into its key and default value.
+// Everything after "default=" is considered to be the default value, so it may contain commas.
+func parseTag(tag string) (key string, def string, hasDef bool) {
+	key, opts, _ := strings.Cut(tag, ",")
+	key = strings.TrimSpace(key)
+
+	def, hasDef = strings.CutPrefix(opts, "default=")
+	return
+}
+
+// bindTypes returns the feature toggle types accepted for a given field type,
+// and whether the field type is supported.
+func bindTypes(t reflect.Type) ([]string, bool) {
+	switch t {
+	case durationType:
+		return []string{"duration", "string"}, true
+	case timeType:
+		return []string{"datetime"}, true
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return []string{"boolean"}, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return []string{"integer", "number"}, true
+	case reflect.Float32, reflect.Float64:
+		return []string{"number", "integer"}, true
+	case reflect.String:
+		return []string{"string", "secret"}, true
+	case reflect.Slice:
+		return []string{"list", "json", "string"}, true
+	case reflect.Map:
+		return []string{"json", "string"}, true
+	}
+
+	return nil, false
+}
+
+// setValue parses the raw feature toggle value into the field v, according to the field type.
+func setValue(v reflect.Value, raw string) error {
+	switch v.Type() {
+	case durationType:
+		d, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	case timeType:
+		t, err := time.Parse(time.RFC3339, strings.TrimSpace(raw))
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(raw), 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(strings.TrimSpace(raw), 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(strings.TrimSpace(raw), v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Slice, reflect.Map:
+		p := reflect.New(v.Type())
+		err := json.Unmarshal([]byte(raw), p.Interface())
+		if err != nil {
+			return err
+		}
+		v.Set(p.Elem())
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+// populate sets every field of the plan on the struct v,
+// using the feature toggles in the local memory, or the field default value.
+func populate(v reflect.Value, plan []fieldBinding) {
+	for _, fb := range plan {
+		field := v.FieldByIndex(fb.index)
+
+		err := bindField(field, fb)
+		if err == nil {
+			continue
+		}
+
+		// logged through the rate limit, since every binding is populated again on each cache rebuild
+		logMiss(context.Background(), "Bind", fb.key, err)
+		if fb.defaultVal.IsValid() {
+			field.Set(fb.defaultVal)
+		}
+	}
+}
+
+// bindField sets the feature toggle value of fb on the field,
+// returns an error if the value could not be found, or is not valid.
+func bindField(field reflect.Value, fb fieldBinding) error {
+	e, err := lookup(context.Background(), fb.key, true)
+	if err != nil {
+		return err
+	}
+
+	if !fb.accepts(e.typ) {
+		return &KeyError{Key: fb.key, Kind: ErrTypeMismatch, ExpectedTypes: fb.types, FoundType: e.typ}
+	}
+
+	// parse into a temporary value, so the field is not left half set on failure
+	tmp := reflect.New(field.Type()).Elem()
+	err = setValue(tmp, e.val)
+	if err != nil {
+		return &KeyError{Key: fb.key, Kind: ErrParse, Err: err}
+	}
+
+	field.Set(tmp)
+	return nil
+}
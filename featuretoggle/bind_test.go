@@ -0,0 +1,211 @@
+package featuretoggle
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindPaymentConfig struct {
+	Gateway string `ft:"gateway,default=stripe"`
+	Retries int    `ft:"retries,default=3"`
+}
+
+type bindConfig struct {
+	Enabled  bool              `ft:"checkout.enabled"`
+	MaxItems int               `ft:"checkout.max_items,default=10"`
+	Ratio    float64           `ft:"checkout.ratio,default=0.5"`
+	Timeout  time.Duration     `ft:"checkout.timeout,default=2s"`
+	Methods  []string          `ft:"checkout.methods,default=[\"card\",\"pix\"]"`
+	Limits   map[string]int    `ft:"checkout.limits"`
+	Payment  bindPaymentConfig `ft:"payment"`
+	Ignored  string
+}
+
+func TestBind(t *testing.T) {
+	t.Run("Should return an error if the target is not a pointer to a struct", func(t *testing.T) {
+		n := 10
+		_, _, err := Bind(&n)
+		if err == nil {
+			t.Errorf("Should have returned an error if the target was not a struct")
+		}
+	})
+	t.Run("Should return an error if a field has an unsupported type", func(t *testing.T) {
+		type invalid struct {
+			Ch chan int `ft:"my.channel"`
+		}
+
+		_, _, err := Bind(&invalid{})
+		if err == nil {
+			t.Errorf("Should have returned an error if a field had an unsupported type")
+		}
+	})
+	t.Run("Should return an error if a default value is invalid", func(t *testing.T) {
+		type invalid struct {
+			N int `ft:"my.number,default=ten"`
+		}
+
+		_, _, err := Bind(&invalid{})
+		if err == nil {
+			t.Errorf("Should have returned an error if a default value was invalid")
+		}
+	})
+	t.Run("Should populate the struct with the default values if the library was not initiated", func(t *testing.T) {
+		Mock(nil)
+
+		cfg := bindConfig{Ignored: "untouched"}
+		_, _, err := Bind(&cfg)
+		if err != nil {
+			t.Fatalf("Should not have returned an error, returned %v", err)
+		}
+
+		expected := bindConfig{
+			MaxItems: 10,
+			Ratio:    0.5,
+			Timeout:  2 * time.Second,
+			Methods:  []string{"card", "pix"},
+			Payment:  bindPaymentConfig{Gateway: "stripe", Retries: 3},
+			Ignored:  "untouched",
+		}
+		if !reflect.DeepEqual(expected, cfg) {
+			t.Errorf("Should have populated the default values, populated %+v", cfg)
+		}
+	})
+	t.Run("Should populate the struct with the feature toggles values", func(t *testing.T) {
+		Mock(map[string]string{
+			"checkout.enabled":        "true",
+			"checkout.enabled.type":   "boolean",
+			"checkout.max_items":      "25",
+			"checkout.max_items.type": "number",
+			"checkout.timeout":        "1m30s",
+			"checkout.timeout.type":   "string",
+			"checkout.limits":         `{"daily": 5}`,
+			"checkout.limits.type":    "string",
+			"payment.gateway":         "adyen",
+			"payment.gateway.type":    "string",
+		})
+		defer Reset()
+
+		var cfg bindConfig
+		_, _, err := Bind(&cfg)
+		if err != nil {
+			t.Fatalf("Should not have returned an error, returned %v", err)
+		}
+
+		expected := bindConfig{
+			Enabled:  true,
+			MaxItems: 25,
+			Ratio:    0.5,
+			Timeout:  90 * time.Second,
+			Methods:  []string{"card", "pix"},
+			Limits:   map[string]int{"daily": 5},
+			Payment:  bindPaymentConfig{Gateway: "adyen", Retries: 3},
+		}
+		if !reflect.DeepEqual(expected, cfg) {
+			t.Errorf("Should have populated the feature toggles values, populated %+v", cfg)
+		}
+	})
+	t.Run("Should use the default value if the key type does not match the field", func(t *testing.T) {
+		Mock(map[string]string{
+			"checkout.max_items":      "25",
+			"checkout.max_items.type": "string",
+		})
+		defer Reset()
+
+		var cfg bindConfig
+		_, _, err := Bind(&cfg)
+		if err != nil {
+			t.Fatalf("Should not have returned an error, returned %v", err)
+		}
+
+		if cfg.MaxItems != 10 {
+			t.Errorf("Should have used the default value if the type did not match, used %v", cfg.MaxItems)
+		}
+	})
+	t.Run("Should refresh the bound copy when the feature toggles change", func(t *testing.T) {
+		Mock(map[string]string{
+			"checkout.max_items":      "25",
+			"checkout.max_items.type": "number",
+		})
+		defer Reset()
+
+		var cfg bindConfig
+		b, _, err := Bind(&cfg)
+		if err != nil {
+			t.Fatalf("Should not have returned an error, returned %v", err)
+		}
+
+		Mock(map[string]string{
+			"checkout.max_items":      "40",
+			"checkout.max_items.type": "number",
+		})
+
+		if b.Load().MaxItems != 40 {
+			t.Errorf("Should have refreshed the bound copy, returned %v", b.Load().MaxItems)
+		}
+		if cfg.MaxItems != 25 {
+			t.Errorf("Should not have changed the original target, returned %v", cfg.MaxItems)
+		}
+	})
+	t.Run("Should stop refreshing the bound copy when unbound", func(t *testing.T) {
+		Mock(map[string]string{
+			"checkout.max_items":      "25",
+			"checkout.max_items.type": "number",
+		})
+		defer Reset()
+
+		var cfg bindConfig
+		b, unbind, err := Bind(&cfg)
+		if err != nil {
+			t.Fatalf("Should not have returned an error, returned %v", err)
+		}
+		kept, _, _ := Bind(&cfg)
+
+		unbind()
+		unbind()
+		Mock(map[string]string{
+			"checkout.max_items":      "40",
+			"checkout.max_items.type": "number",
+		})
+
+		if b.Load().MaxItems != 25 {
+			t.Errorf("Should not have refreshed the unbound copy, returned %v", b.Load().MaxItems)
+		}
+		if kept.Load().MaxItems != 40 {
+			t.Errorf("Should have refreshed the other bound copies, returned %v", kept.Load().MaxItems)
+		}
+		if len(bindings) != 1 {
+			t.Errorf("Should have removed the unbound copy, kept %d bindings", len(bindings))
+		}
+
+		Reset()
+		if len(bindings) != 0 {
+			t.Errorf("Should have removed every binding on reset, kept %d bindings", len(bindings))
+		}
+	})
+	t.Run("Should log the bind failures once per interval", func(t *testing.T) {
+		Mock(map[string]string{
+			"checkout.max_items":      "many",
+			"checkout.max_items.type": "number",
+		})
+		defer Reset()
+		rec := &recordLogger{}
+		setLogger(rec, time.Hour)
+
+		var cfg struct {
+			MaxItems int `ft:"checkout.max_items,default=10"`
+		}
+		_, _, err := Bind(&cfg)
+		if err != nil {
+			t.Fatalf("Should not have returned an error, returned %v", err)
+		}
+		for i := 0; i < 10; i++ {
+			refreshBindings()
+		}
+
+		if len(rec.msgs) != 1 || !strings.HasPrefix(rec.msgs[0], "Bind for key checkout.max_items") {
+			t.Errorf("Should have logged the bind failure once, logged %v", rec.msgs)
+		}
+	})
+}
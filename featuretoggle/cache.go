@@ -0,0 +1,372 @@
+package featuretoggle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// the compiled feature toggles currently in use, nil if the library was not initiated
+	cache atomic.Pointer[snapshot]
+	// whether every feature toggle keeps its last known good value when updated with an invalid value
+	keepLastKnownGood atomic.Bool
+)
+
+// snapshot represents all of the service feature toggles saved in memory,
+// with their values already parsed according to their declared types.
+type snapshot struct {
+	// the raw key-value pairs, as found in redis
+	raw map[string]string
+	// a hash of the raw key-value pairs (see Status)
+	hash string
+	// the version of the feature toggles (see VersionField)
+	version int64
+	// the parsed feature toggles, by key
+	entries map[string]*entry
+	// the feature toggles rejected because of their constraints, sorted by key
+	violations []*Violation
+	// the feature toggles that kept their last known good values, sorted by key
+	fallbacks []Event
+}
+
+// entry represents a single feature toggle, parsed when the cache is built.
+type entry struct {
+	// the raw value of the feature toggle
+	val string
+	// whether the raw value is empty
+	blank bool
+	// the declared type of the feature toggle (the "<key>.type" field)
+	typ string
+	// whether the declared type was found and is not empty
+	hasType bool
+
+	// the value parsed as a boolean, valid only if boolOK
+	boolean bool
+	boolOK  bool
+	// the value parsed as a number, valid only if numberOK
+	number   float64
+	numberOK bool
+	// the value parsed as an integer, valid only if intOK
+	integer int64
+	intOK   bool
+	// the value parsed as a duration, valid only if durationOK
+	duration   time.Duration
+	durationOK bool
+	// the value parsed as a datetime, valid only if timeOK
+	time   time.Time
+	timeOK bool
+	// the value parsed as a list of strings, valid only if listOK
+	list   []string
+	listOK bool
+	// the error found when parsing the value into its declared type, if any
+	err error
+	// the constraint violated by the value, if any
+	violation *Violation
+
+	// the scheduled values of the feature toggle, sorted by time (see the "<key>.schedule" metadata field)
+	schedule []transition
+	// the violations of the invalid scheduled values, rejected only when they start
+	scheduleViolations []*Violation
+
+	// the memoised Get[T] decodes of the value, by reflect.Type
+	decoded sync.Map
+}
+
+// decoded represents a memoised Get[T] decode of a feature toggle value
+type decoded struct {
+	val any
+	err error
+}
+
+// memoisableTypes caches the result of memoisable, by reflect.Type
+var memoisableTypes sync.Map
+
+// memoisable checks if the decoded values of the type can be shared between the Get callers,
+// that is, if they hold no references (maps, slices, pointers, etc.) a caller could change.
+func memoisable(t reflect.Type) bool {
+	if ok, found := memoisableTypes.Load(t); found {
+		return ok.(bool)
+	}
+
+	ok := !holdsReferences(t)
+	memoisableTypes.Store(t, ok)
+	return ok
+}
+
+// holdsReferences checks if the values of the type hold references, time.Time is treated as a value.
+func holdsReferences(t reflect.Type) bool {
+	if t == timeType {
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Pointer, reflect.Interface, reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return true
+	case reflect.Array:
+		return holdsReferences(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if holdsReferences(t.Field(i).Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// compile parses all of the raw feature toggles into a snapshot.
+// The previous snapshot (if any) is used to keep the last known good values of the feature toggles
+// in last known good mode, and of the feature toggles rejected by their json schema.
+func compile(raw map[string]string, prev *snapshot) *snapshot {
+	s := &snapshot{
+		raw:     raw,
+		hash:    hashRaw(raw),
+		version: versionOf(raw),
+		entries: make(map[string]*entry, len(raw)),
+	}
+
+	for key := range raw {
+		e, _ := compileEntry(raw, key)
+		if e.violation != nil {
+			s.violations = append(s.violations, e.violation)
+		}
+		s.violations = append(s.violations, e.scheduleViolations...)
+
+		last := prev.lastValid(key)
+		schemaViolation := e.violation != nil && e.violation.Constraint == "schema"
+		if last != nil && (schemaViolation || lastKnownGoodMode(key, raw)) {
+			if err := e.rejection(last); err != nil {
+				if e.violation != nil {
+					e.violation.KeptLastValid = true
+				}
+
+				s.fallbacks = append(s.fallbacks, Event{Type: EventLastKnownGood, Key: key, Value: e.redacted(e.val), Err: err})
+				e = last
+			}
+		}
+
+		s.entries[key] = e
+	}
+
+	sort.Slice(s.violations, func(i, j int) bool {
+		return s.violations[i].Key < s.violations[j].Key
+	})
+	sort.Slice(s.fallbacks, func(i, j int) bool {
+		return s.fallbacks[i].Key < s.fallbacks[j].Key
+	})
+	return s
+}
+
+// lastKnownGoodMode checks if the feature toggle keeps its last known good value when updated with an invalid value,
+// either because of the library configuration, or because of its "<key>.fallback" metadata field.
+func lastKnownGoodMode(key string, raw map[string]string) bool {
+	if keepLastKnownGood.Load() {
+		return true
+	}
+
+	fallback, _ := metadata(raw, key, "fallback")
+	return fallback == "last_known_good"
+}
+
+// rejection returns the reason the entry should be replaced by the last known good entry, or nil if it is good.
+// Empty values are not rejected, since they mean the feature toggle was intentionally removed.
+func (e *entry) rejection(last *entry) error {
+	switch {
+	case e.blank:
+		return nil
+	case !e.hasType && last.hasType:
+		return fmt.Errorf("the value type was not found or empty")
+	case e.err != nil:
+		return e.err
+	case e.violation != nil:
+		return e.violation
+	}
+
+	return nil
+}
+
+// scheduled checks if the violation is of a scheduled value (see the "<key>.schedule" metadata field).
+func (s *snapshot) scheduled(v *Violation) bool {
+	e, ok := s.entries[v.Key]
+	if !ok {
+		return false
+	}
+
+	for _, sv := range e.scheduleViolations {
+		if sv == v {
+			return true
+		}
+	}
+	return false
+}
+
+// lastValid returns the entry of the key if it is valid, or nil.
+func (s *snapshot) lastValid(key string) *entry {
+	if s == nil {
+		return nil
+	}
+
+	e, ok := s.entries[key]
+	if !ok || e.blank || e.err != nil || e.violation != nil {
+		return nil
+	}
+	return e
+}
+
+// parse parses the entry value according to its declared type.
+func parse(e *entry) {
+	switch e.typ {
+	case "boolean":
+		e.boolean, e.err = strconv.ParseBool(e.val)
+		e.boolOK = e.err == nil
+	case "number":
+		e.number, e.err = strconv.ParseFloat(e.val, 64)
+		e.numberOK = e.err == nil
+
+		// numbers may also be used as integers (ex.: IsEnabledByPercent)
+		i, err := strconv.ParseInt(e.val, 10, 64)
+		e.integer, e.intOK = i, err == nil
+	case "integer":
+		e.integer, e.err = strconv.ParseInt(e.val, 10, 64)
+		e.intOK = e.err == nil
+
+		// integers are also valid numbers
+		e.number, e.numberOK = float64(e.integer), e.intOK
+	case "duration":
+		e.duration, e.err = time.ParseDuration(e.val)
+		e.durationOK = e.err == nil
+	case "datetime":
+		e.time, e.err = time.Parse(time.RFC3339, e.val)
+		e.timeOK = e.err == nil
+	case "list":
+		var items []json.RawMessage
+		e.err = json.Unmarshal([]byte(e.val), &items)
+		if e.err != nil {
+			return
+		}
+
+		// lists of other types are still valid, but can only be read through Get
+		var list []string
+		if json.Unmarshal([]byte(e.val), &list) == nil {
+			e.list, e.listOK = list, true
+		}
+	case "json":
+		if !json.Valid([]byte(e.val)) {
+			e.err = fmt.Errorf("invalid json value")
+		}
+	}
+}
+
+// entry returns the entry of the key in use now (see the "<key>.schedule" metadata field), if the snapshot has it.
+func (s *snapshot) entry(key string) (*entry, bool) {
+	if s == nil {
+		return nil, false
+	}
+
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	return e.at(time.Now()), true
+}
+
+// metadataFields are the "<key>.<field>" metadata fields of the feature toggles
+var metadataFields = []string{"type", "enum", "min", "max", "pattern", "schema", "fallback", "schedule"}
+
+// keys returns the number of feature toggles in the snapshot, without their metadata fields and the VersionField.
+func (s *snapshot) keys() int {
+	if s == nil {
+		return 0
+	}
+
+	n := 0
+	for key := range s.raw {
+		if key != VersionField && !isMetadata(key, s.raw) {
+			n++
+		}
+	}
+	return n
+}
+
+// isMetadata checks if the field is a metadata field of another feature toggle in raw (ex.: "<key>.type").
+func isMetadata(field string, raw map[string]string) bool {
+	for _, m := range metadataFields {
+		key, ok := strings.CutSuffix(field, "."+m)
+		if !ok {
+			continue
+		}
+		if _, ok := raw[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// current returns the snapshot currently in use, or nil if the library was not initiated.
+func current() *snapshot {
+	return cache.Load()
+}
+
+// store compiles the raw feature toggles and swaps the snapshot in use.
+// A nil map leaves the library in the not initiated state.
+func store(raw map[string]string) {
+	if raw == nil {
+		cache.Store(nil)
+		watchSchedules(nil)
+		refreshBindings()
+		return
+	}
+
+	prev := current()
+	s := compile(raw, prev)
+	if prev != nil {
+		recordChanges(prev.raw, raw)
+	}
+	for _, v := range s.violations {
+		title := "[Feature Toggle] The value was rejected by its constraints, the default value will be used"
+		switch {
+		case v.KeptLastValid:
+			title = "[Feature Toggle] The value was rejected by its constraints, the last valid value will be kept"
+		case s.scheduled(v):
+			title = "[Feature Toggle] The scheduled value was rejected by its constraints, the default value will be used when it starts"
+		}
+
+		logInfo(context.Background(), title,
+			"key", v.Key,
+			"constraint", v.Constraint,
+			"error", v.Err.Error(),
+		)
+	}
+
+	for _, f := range s.fallbacks {
+		if _, ok := f.Err.(*Violation); ok {
+			// already logged as a violation
+			continue
+		}
+
+		logInfo(context.Background(), "[Feature Toggle] The value is invalid, the last known good value will be kept",
+			"key", f.Key,
+			"error", f.Err.Error(),
+		)
+	}
+
+	cache.Store(s)
+
+	for _, v := range s.violations {
+		emit(Event{Type: EventConstraintViolation, Key: v.Key, Value: v.Value, Err: v})
+	}
+	for _, f := range s.fallbacks {
+		emit(f)
+	}
+
+	watchSchedules(s)
+	refreshBindings()
+}
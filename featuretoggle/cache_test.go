@@ -0,0 +1,258 @@
+package featuretoggle
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+var benchToggles = map[string]string{
+	"MyBool":         "true",
+	"MyBool.type":    "boolean",
+	"MyNumber":       "42.5",
+	"MyNumber.type":  "number",
+	"MyPercent":      "50",
+	"MyPercent.type": "number",
+	"MyString":       "my value",
+	"MyString.type":  "string",
+	"MyJSON":         `{"mykey1": "myval", "mykey2": 20}`,
+}
+
+type benchStruct struct {
+	MyKey1 string `json:"mykey1"`
+	MyKey2 int    `json:"mykey2"`
+}
+
+func TestCompile(t *testing.T) {
+	t.Run("Should parse the values according to their declared types", func(t *testing.T) {
+		s := compile(benchToggles, nil)
+
+		if e := s.entries["MyBool"]; !e.boolOK || !e.boolean {
+			t.Errorf("Should have parsed the boolean value, parsed %+v", e)
+		}
+		if e := s.entries["MyNumber"]; !e.numberOK || e.number != 42.5 || e.intOK {
+			t.Errorf("Should have parsed the number value, parsed %+v", e)
+		}
+		if e := s.entries["MyPercent"]; !e.intOK || e.integer != 50 {
+			t.Errorf("Should have parsed the integer value, parsed %+v", e)
+		}
+		if e := s.entries["MyString"]; e.boolOK || e.numberOK || e.typ != "string" {
+			t.Errorf("Should not have parsed the string value, parsed %+v", e)
+		}
+		if e := s.entries["MyJSON"]; e.hasType {
+			t.Errorf("Should not have found a type for the value, found %+v", e)
+		}
+	})
+	t.Run("Should memoise the Get decodes until the feature toggles change", func(t *testing.T) {
+		Mock(benchToggles)
+		defer Reset()
+
+		Get("MyJSON", benchStruct{})
+		if _, ok := current().entries["MyJSON"].decoded.Load(reflect.TypeOf(benchStruct{})); !ok {
+			t.Errorf("Should have memoised the decoded value")
+		}
+
+		Mock(benchToggles)
+		if _, ok := current().entries["MyJSON"].decoded.Load(reflect.TypeOf(benchStruct{})); ok {
+			t.Errorf("Should have decoded the value again after the cache was rebuilt")
+		}
+	})
+	t.Run("Should not share the Get decodes of types with references", func(t *testing.T) {
+		Mock(benchToggles)
+		defer Reset()
+
+		first := Get("MyJSON", map[string]any{})
+		second := Get("MyJSON", map[string]any{})
+		first["mykey1"] = "changed"
+		if second["mykey1"] != "myval" {
+			t.Errorf("Should not have shared the map between the callers, returned %v", second)
+		}
+
+		type withSlice struct {
+			MyKey1 string `json:"mykey1"`
+			Tags   []string
+		}
+		for _, typ := range []reflect.Type{reflect.TypeOf(map[string]any{}), reflect.TypeOf(withSlice{}), reflect.TypeOf(&benchStruct{})} {
+			if memoisable(typ) {
+				t.Errorf("Should not have memoised the type %v", typ)
+			}
+		}
+		for _, typ := range []reflect.Type{reflect.TypeOf(benchStruct{}), reflect.TypeOf([2]int{}), reflect.TypeOf(time.Time{})} {
+			if !memoisable(typ) {
+				t.Errorf("Should have memoised the type %v", typ)
+			}
+		}
+	})
+	t.Run("Should not allocate when reading scalar toggles", func(t *testing.T) {
+		Mock(benchToggles)
+		defer Reset()
+
+		allocs := testing.AllocsPerRun(100, func() {
+			IsEnabled("MyBool", false)
+			GetNumber("MyNumber", 0)
+			GetString("MyString", "")
+			IsEnabledByPercent("MyPercent")
+			Get("MyJSON", benchStruct{})
+		})
+		if allocs != 0 {
+			t.Errorf("Should not have allocated when reading the toggles, allocated %v times", allocs)
+		}
+	})
+}
+
+func BenchmarkIsEnabled(b *testing.B) {
+	Mock(benchToggles)
+	defer Reset()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		IsEnabled("MyBool", false)
+	}
+}
+
+func BenchmarkGetNumber(b *testing.B) {
+	Mock(benchToggles)
+	defer Reset()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		GetNumber("MyNumber", 0)
+	}
+}
+
+func BenchmarkGetString(b *testing.B) {
+	Mock(benchToggles)
+	defer Reset()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		GetString("MyString", "")
+	}
+}
+
+func BenchmarkIsEnabledByPercent(b *testing.B) {
+	Mock(benchToggles)
+	defer Reset()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		IsEnabledByPercent("MyPercent")
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	Mock(benchToggles)
+	defer Reset()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Get("MyJSON", benchStruct{})
+	}
+}
+
+func TestLastKnownGood(t *testing.T) {
+	valid := map[string]string{
+		"timeout":                  "30",
+		"timeout.type":             "number",
+		"timeout.fallback":         "last_known_good",
+		"other.timeout":            "30",
+		"other.timeout.type":       "number",
+		"checkout.limits":          `{"daily": 5}`,
+		"checkout.limits.type":     "json",
+		"checkout.limits.fallback": "last_known_good",
+	}
+
+	t.Run("Should keep the last known good value when the new value is invalid", func(t *testing.T) {
+		Mock(valid)
+		defer Reset()
+
+		var events []Event
+		unregister := OnEvent(func(e Event) {
+			if e.Type == EventLastKnownGood {
+				events = append(events, e)
+			}
+		})
+		defer unregister()
+
+		Mock(map[string]string{
+			"timeout":                  "thirty",
+			"timeout.type":             "number",
+			"timeout.fallback":         "last_known_good",
+			"other.timeout":            "thirty",
+			"other.timeout.type":       "number",
+			"checkout.limits":          `{"daily": 5`,
+			"checkout.limits.type":     "json",
+			"checkout.limits.fallback": "last_known_good",
+		})
+
+		if actual := GetNumber("timeout", 10); actual != 30 {
+			t.Errorf("Should have kept the last known good value, actualy returned %v", actual)
+		}
+		if actual := Get("checkout.limits", map[string]int{}); actual["daily"] != 5 {
+			t.Errorf("Should have kept the last known good value, actualy returned %v", actual)
+		}
+		if actual := GetNumber("other.timeout", 10); actual != 10 {
+			t.Errorf("Should have returned the default value for keys without the fallback mode, actualy returned %v", actual)
+		}
+
+		if len(events) != 2 || events[0].Type != EventLastKnownGood || events[0].Key != "checkout.limits" || events[1].Key != "timeout" {
+			t.Errorf("Should have emitted the last known good events, emitted %+v", events)
+		}
+	})
+	t.Run("Should keep the last known good value when the type is removed", func(t *testing.T) {
+		Mock(valid)
+		defer Reset()
+
+		Mock(map[string]string{
+			"timeout":          "45",
+			"timeout.fallback": "last_known_good",
+		})
+
+		if actual := GetNumber("timeout", 10); actual != 30 {
+			t.Errorf("Should have kept the last known good value, actualy returned %v", actual)
+		}
+	})
+	t.Run("Should apply a valid type change", func(t *testing.T) {
+		Mock(valid)
+		defer Reset()
+
+		Mock(map[string]string{
+			"timeout":          "45s",
+			"timeout.type":     "duration",
+			"timeout.fallback": "last_known_good",
+		})
+
+		if actual := GetDuration("timeout", 0); actual != 45*time.Second {
+			t.Errorf("Should have applied the new type, actualy returned %v", actual)
+		}
+		if actual := GetNumber("timeout", 10); actual != 10 {
+			t.Errorf("Should not have kept the previous type, actualy returned %v", actual)
+		}
+	})
+	t.Run("Should keep the last known good value of every key if configured", func(t *testing.T) {
+		Mock(valid)
+		defer Reset()
+		keepLastKnownGood.Store(true)
+
+		Mock(map[string]string{
+			"other.timeout":      "thirty",
+			"other.timeout.type": "number",
+		})
+
+		if actual := GetNumber("other.timeout", 10); actual != 30 {
+			t.Errorf("Should have kept the last known good value, actualy returned %v", actual)
+		}
+	})
+	t.Run("Should not keep the last known good value of removed keys", func(t *testing.T) {
+		Mock(valid)
+		defer Reset()
+
+		Mock(map[string]string{
+			"timeout.fallback": "last_known_good",
+		})
+
+		if actual := GetNumber("timeout", 10); actual != 10 {
+			t.Errorf("Should have returned the default value, actualy returned %v", actual)
+		}
+	})
+}
@@ -0,0 +1,172 @@
+package featuretoggle
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Types are the types accepted in the "<key>.type" field
+var Types = []string{"boolean", "number", "integer", "string", "secret", "duration", "datetime", "list", "json"}
+
+// Keys returns the feature toggle keys of the raw feature toggles (in the format saved in redis), sorted,
+// without their metadata fields (like "<key>.type") and the VersionField.
+func Keys(raw map[string]string) []string {
+	keys := make([]string, 0, len(raw))
+	for field := range raw {
+		if field != VersionField && !isMetadata(field, raw) {
+			keys = append(keys, field)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// MetadataFields returns the metadata fields of the key found in the raw feature toggles (like "<key>.type"), sorted.
+func MetadataFields(raw map[string]string, key string) []string {
+	var fields []string
+	for _, m := range metadataFields {
+		field := key + "." + m
+		if _, ok := raw[field]; ok {
+			fields = append(fields, field)
+		}
+	}
+
+	sort.Strings(fields)
+	return fields
+}
+
+/*
+Check checks the feature toggle of the key in the raw feature toggles (in the format saved in redis, with their metadata fields),
+the same way the library would when loading them. Useful to validate the feature toggles before writing them to redis.
+
+returns a *KeyError (see KeyError) if the feature toggle:
+
+- was not found, or has an empty value (ErrNotFound);
+
+- has no "<key>.type" field, or the field is empty (ErrTypeMissing);
+
+- has a "<key>.type" field that is not one of the Types (ErrTypeMismatch);
+
+- has a value that could not be parsed into its type (ErrParse);
+
+- has a value, or a scheduled value, that violates its constraints (ErrConstraintViolation).
+
+returns nil if the feature toggle is valid.
+*/
+func Check(raw map[string]string, key string) error {
+	e, ok := compileEntry(raw, key)
+	switch {
+	case !ok || e.blank:
+		return &KeyError{Key: key, Kind: ErrNotFound}
+	case !e.hasType:
+		return &KeyError{Key: key, Kind: ErrTypeMissing}
+	case !knownType(e.typ):
+		return &KeyError{Key: key, Kind: ErrTypeMismatch, ExpectedTypes: Types, FoundType: e.typ}
+	case e.err != nil:
+		return &KeyError{Key: key, Kind: ErrParse, Err: e.err}
+	case e.violation != nil:
+		return &KeyError{Key: key, Kind: ErrConstraintViolation, Err: e.violation}
+	case len(e.scheduleViolations) > 0:
+		return &KeyError{Key: key, Kind: ErrConstraintViolation, Err: e.scheduleViolations[0]}
+	}
+
+	return nil
+}
+
+// EvaluateRaw evaluates the feature toggle of the key in the raw feature toggles (in the format saved in redis),
+// without loading them (see Evaluate), with the value scheduled for now, if any.
+func EvaluateRaw(raw map[string]string, key string) Evaluation {
+	e, ok := compileEntry(raw, key)
+	if ok && e.violation == nil {
+		e = e.at(time.Now())
+	}
+	if !ok || e.blank {
+		return evaluate(key, e, &KeyError{Key: key, Kind: ErrNotFound}, ReasonStatic)
+	}
+
+	var err error
+	if e.violation != nil {
+		err = &KeyError{Key: key, Kind: ErrConstraintViolation, Err: e.violation}
+	}
+	return evaluate(key, e, err, ReasonStatic)
+}
+
+// compileEntry parses the feature toggle of the key in the raw feature toggles, and checks its constraints.
+// returns false if the key was not found.
+func compileEntry(raw map[string]string, key string) (*entry, bool) {
+	val, ok := raw[key]
+	if !ok {
+		return nil, false
+	}
+
+	e := compileValue(raw, key, val)
+	if e.violation == nil {
+		e.violation = compileSchedule(raw, key, e)
+	}
+	return e, true
+}
+
+// compileValue parses the value as the value of the key in the raw feature toggles, and checks its constraints.
+func compileValue(raw map[string]string, key string, val string) *entry {
+	e := &entry{
+		val:   val,
+		blank: strings.TrimSpace(val) == "",
+		typ:   raw[key+".type"],
+	}
+	e.hasType = strings.TrimSpace(e.typ) != ""
+
+	parse(e)
+	e.violation = checkConstraints(key, e, raw)
+	if e.violation == nil {
+		e.violation = checkSchema(key, e, raw)
+	}
+	return e
+}
+
+// secretMetadata are the metadata fields that hold values of the feature toggle, redacted like the values of "secret" feature toggles
+var secretMetadata = []string{"enum", "schedule"}
+
+// Secret checks if the field of the raw feature toggles holds a value of a feature toggle of type "secret":
+// the feature toggle itself, or one of its metadata fields with values of the feature toggle (see SecretMetadata).
+func Secret(raw map[string]string, field string) bool {
+	if raw[field+".type"] == "secret" {
+		return true
+	}
+
+	key, name, ok := cutMetadata(field)
+	return ok && SecretMetadata(name) && raw[key+".type"] == "secret"
+}
+
+// SecretMetadata checks if the metadata field (like "enum" or "schedule") holds values of the feature toggle,
+// so that it must be redacted like the value of a "secret" feature toggle.
+func SecretMetadata(name string) bool {
+	for _, m := range secretMetadata {
+		if m == name {
+			return true
+		}
+	}
+	return false
+}
+
+// cutMetadata splits a metadata field (like "<key>.enum") into its key and name.
+// returns false if the field does not end with a metadata name.
+func cutMetadata(field string) (string, string, bool) {
+	for _, m := range metadataFields {
+		if key, ok := strings.CutSuffix(field, "."+m); ok {
+			return key, m, true
+		}
+	}
+	return "", "", false
+}
+
+// knownType checks if the type is one of the Types.
+func knownType(typ string) bool {
+	for _, t := range Types {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package featuretoggle
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	raw := map[string]string{
+		"MyBool":         "true",
+		"MyBool.type":    "boolean",
+		"MyNumber":       "ten",
+		"MyNumber.type":  "number",
+		"MyLimit":        "20",
+		"MyLimit.type":   "integer",
+		"MyLimit.max":    "10",
+		"MyUnknown":      "1",
+		"MyUnknown.type": "bool",
+		"MyUntyped":      "1",
+		"MyEmpty":        " ",
+		"MyEmpty.type":   "string",
+	}
+
+	tests := []struct {
+		key      string
+		expected error
+	}{
+		{"MyBool", nil},
+		{"MyNumber", ErrParse},
+		{"MyLimit", ErrConstraintViolation},
+		{"MyUnknown", ErrTypeMismatch},
+		{"MyUntyped", ErrTypeMissing},
+		{"MyEmpty", ErrNotFound},
+		{"MyMissing", ErrNotFound},
+	}
+	for _, tt := range tests {
+		err := Check(raw, tt.key)
+		if !errors.Is(err, tt.expected) || (tt.expected == nil && err != nil) {
+			t.Errorf("Should have returned %v for key %s, returned %v", tt.expected, tt.key, err)
+		}
+	}
+
+	keys := Keys(raw)
+	if len(keys) != 6 || keys[0] != "MyBool" {
+		t.Errorf("Should have returned the keys without their metadata fields, returned %v", keys)
+	}
+	if fields := MetadataFields(raw, "MyLimit"); len(fields) != 2 || fields[0] != "MyLimit.max" {
+		t.Errorf("Should have returned the metadata fields of the key, returned %v", fields)
+	}
+}
+
+func TestEvaluateRaw(t *testing.T) {
+	raw := map[string]string{
+		"MyList":       `["a", "b"]`,
+		"MyList.type":  "list",
+		"MyLimit":      "20",
+		"MyLimit.type": "integer",
+		"MyLimit.max":  "10",
+	}
+
+	ev := EvaluateRaw(raw, "MyList")
+	if list, ok := ev.Value.([]any); !ok || len(list) != 2 || ev.Reason != ReasonStatic || ev.Err != nil {
+		t.Errorf("Should have evaluated the list, returned %+v", ev)
+	}
+
+	ev = EvaluateRaw(raw, "MyLimit")
+	if !errors.Is(ev.Err, ErrConstraintViolation) || ev.Reason != ReasonError || ev.Raw != "20" {
+		t.Errorf("Should have returned the constraint violation, returned %+v", ev)
+	}
+
+	if current() != nil && len(current().raw) != 0 {
+		t.Errorf("Should not have loaded the feature toggles")
+	}
+}
+
+func TestSecret(t *testing.T) {
+	raw := map[string]string{
+		"MyToken":          "s3cr3t",
+		"MyToken.type":     "secret",
+		"MyToken.enum":     `["s3cr3t"]`,
+		"MyToken.schedule": `{"transitions": []}`,
+		"MyToken.pattern":  "^s",
+		"MyString":         "value",
+		"MyString.type":    "string",
+		"MyString.enum":    `["value"]`,
+	}
+
+	for field, expected := range map[string]bool{
+		"MyToken":          true,
+		"MyToken.enum":     true,
+		"MyToken.schedule": true,
+		"MyToken.type":     false,
+		"MyToken.pattern":  false,
+		"MyString":         false,
+		"MyString.enum":    false,
+	} {
+		if Secret(raw, field) != expected {
+			t.Errorf("Expected Secret(%s) to be %v", field, expected)
+		}
+	}
+}
@@ -1,9 +1,41 @@
 package featuretoggle
 
+import "time"
+
 // Config represents the feature toggle configuration
 type Config struct {
 	Host        string
 	Port        string
 	DB          int
 	ServiceName string
+	// Strict makes Init validate every declared flag against the loaded feature toggles (see Validate),
+	// returning the validation errors if any flag is invalid.
+	Strict bool
+	// LastKnownGood makes every feature toggle keep its last known good value when it is updated with an invalid value
+	// (a value that fails to parse, violates its constraints, or has a missing or different type).
+	// Can also be enabled for a single feature toggle, with the "<key>.fallback" metadata field set to "last_known_good".
+	LastKnownGood bool
+	// Logger is the logger used by the library, uses the dm-go logger if nil (see SlogLogger and DMLogger).
+	Logger Logger
+	// LogInterval is the interval in which each distinct miss reason of a key is logged at most once,
+	// when an accessor returns the default value. Uses DefaultLogInterval if zero, and logs every miss if negative.
+	LogInterval time.Duration
+	// MaxStaleness is how long the feature toggles may go without being confirmed to be up to date
+	// (by a sync or by a subscription health check, every 30 seconds) before they are considered stale (see Status).
+	// An EventStale is emitted when they become stale. Zero means they are never considered stale.
+	MaxStaleness time.Duration
+	// Versioned makes the library rebuild the cache only when the VersionField of the service hash changes,
+	// checking it with a single HGET on every update notification instead of loading every feature toggle.
+	// The version is changed by every write made through the admin package, so the multiple notifications of a write are coalesced
+	// into a single rebuild. Changes made without changing the version (like a raw HSET) are loaded with the next versioned write.
+	Versioned bool
+	// RebuildDebounce is the window in which the update notifications are collapsed into a single rebuild of the cache.
+	// At most one rebuild runs at a time, and the notifications received while it runs are collapsed into the next one.
+	// Zero rebuilds the cache as soon as a notification is received.
+	RebuildDebounce time.Duration
+	// ChangeStream makes the library read the changes of the feature toggles from the change stream of the service
+	// (see ChangeStream), instead of reading the whole hash on every change. The changes missed (like when the stream is trimmed)
+	// are found by their version, and the whole hash is read again.
+	// Only the changes written with the change stream (like through the admin package) are read in this mode.
+	ChangeStream bool
 }
@@ -0,0 +1,174 @@
+package featuretoggle
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Violation describes a feature toggle value rejected because it does not satisfy the constraints declared
+// in its metadata fields ("<key>.enum", "<key>.min", "<key>.max", "<key>.pattern", "<key>.schema" or "<key>.schedule").
+type Violation struct {
+	// the feature toggle key
+	Key string
+	// the constraint that was not satisfied ("enum", "min", "max", "pattern", "schema" or "schedule")
+	Constraint string
+	// the rejected value (Redacted for the feature toggles of type "secret")
+	Value string
+	// the reason the value was rejected
+	Err error
+	// whether the last valid value of the feature toggle is still being used
+	KeptLastValid bool
+}
+
+// Error returns the violation message
+func (v *Violation) Error() string {
+	return fmt.Sprintf("the value %q violates the %s constraint: %s", v.Value, v.Constraint, v.Err)
+}
+
+// Unwrap returns the reason the value was rejected
+func (v *Violation) Unwrap() error {
+	return v.Err
+}
+
+// Violations returns the feature toggles currently rejected because of their constraints, sorted by key.
+// Rejected feature toggles behave as if they were invalid, so their default values are used,
+// except for json schema violations, that keep the last valid value of the feature toggle when there is one.
+func Violations() []Violation {
+	s := current()
+	if s == nil {
+		return nil
+	}
+
+	res := make([]Violation, len(s.violations))
+	for i, v := range s.violations {
+		res[i] = *v
+	}
+	return res
+}
+
+/*
+checkConstraints checks the entry value against the constraints declared in the feature toggle metadata fields:
+
+- "<key>.enum": a json array with the allowed values;
+
+- "<key>.min" and "<key>.max": the inclusive bounds of "number", "integer" and "duration" values;
+
+- "<key>.pattern": a regular expression the value must match.
+
+Only values that were parsed successfully are checked.
+Invalid metadata fields reject the value, so that a typo in a constraint never lets an unchecked value through.
+*/
+func checkConstraints(key string, e *entry, raw map[string]string) *Violation {
+	if e.blank || e.err != nil {
+		return nil
+	}
+
+	violation := func(constraint string, err error) *Violation {
+		return &Violation{Key: key, Constraint: constraint, Value: e.redacted(e.val), Err: err}
+	}
+
+	if enum, ok := metadata(raw, key, "enum"); ok {
+		err := checkEnum(e.val, enum, e.redacted(enum))
+		if err != nil {
+			return violation("enum", err)
+		}
+	}
+
+	if min, ok := metadata(raw, key, "min"); ok {
+		c, err := compareBound(e, min)
+		if err == nil && c < 0 {
+			err = fmt.Errorf("the value is lower than %s", min)
+		}
+		if err != nil {
+			return violation("min", err)
+		}
+	}
+
+	if max, ok := metadata(raw, key, "max"); ok {
+		c, err := compareBound(e, max)
+		if err == nil && c > 0 {
+			err = fmt.Errorf("the value is greater than %s", max)
+		}
+		if err != nil {
+			return violation("max", err)
+		}
+	}
+
+	if pattern, ok := metadata(raw, key, "pattern"); ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return violation("pattern", fmt.Errorf("invalid pattern %s: %w", pattern, err))
+		}
+		if !re.MatchString(e.val) {
+			return violation("pattern", fmt.Errorf("the value does not match %s", pattern))
+		}
+	}
+
+	return nil
+}
+
+// metadata returns the "<key>.<field>" metadata field of a feature toggle, and whether it was found and is not empty.
+func metadata(raw map[string]string, key string, field string) (string, bool) {
+	val := strings.TrimSpace(raw[fmt.Sprintf("%s.%s", key, field)])
+	return val, val != ""
+}
+
+// checkEnum checks if the value is one of the values in the enum json array.
+// Strings are compared to the value as is, every other json value is compared by its json representation.
+// The errors show the enum as shown, that is redacted for secrets.
+func checkEnum(val string, enum string, shown string) error {
+	var allowed []json.RawMessage
+	err := json.Unmarshal([]byte(enum), &allowed)
+	if err != nil {
+		return fmt.Errorf("invalid enum %s: %w", shown, err)
+	}
+
+	for _, a := range allowed {
+		var s string
+		if json.Unmarshal(a, &s) != nil {
+			s = string(a)
+		}
+
+		if s == strings.TrimSpace(val) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("the value is not one of %s", shown)
+}
+
+// compareBound compares the entry value with a min or max bound,
+// returns -1, 0 or 1 if the value is lower than, equal to or greater than the bound.
+func compareBound(e *entry, bound string) (int, error) {
+	switch {
+	case e.durationOK:
+		d, err := time.ParseDuration(bound)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration bound %s: %w", bound, err)
+		}
+		return compare(e.duration, d), nil
+	case e.numberOK:
+		n, err := strconv.ParseFloat(bound, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number bound %s: %w", bound, err)
+		}
+		return compare(e.number, n), nil
+	}
+
+	return 0, fmt.Errorf("bounds are not supported for the type %s", e.typ)
+}
+
+// compare returns -1, 0 or 1 if a is lower than, equal to or greater than b.
+func compare[T ~int64 | ~float64](a T, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
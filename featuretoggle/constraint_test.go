@@ -0,0 +1,110 @@
+package featuretoggle
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConstraints(t *testing.T) {
+	t.Run("Should reject string values that are not in the enum", func(t *testing.T) {
+		Mock(map[string]string{
+			"payment.gateway":      "stirpe",
+			"payment.gateway.type": "string",
+			"payment.gateway.enum": `["stripe", "adyen"]`,
+		})
+		defer Reset()
+
+		actual := GetString("payment.gateway", "stripe")
+		if actual != "stripe" {
+			t.Errorf("Should have returned the default value if the value was not in the enum, actualy returned %v", actual)
+		}
+
+		violations := Violations()
+		if len(violations) != 1 || violations[0].Key != "payment.gateway" || violations[0].Constraint != "enum" {
+			t.Errorf("Should have reported the enum violation, reported %+v", violations)
+		}
+	})
+	t.Run("Should accept values that are in the enum", func(t *testing.T) {
+		Mock(map[string]string{
+			"payment.gateway":      "adyen",
+			"payment.gateway.type": "string",
+			"payment.gateway.enum": `["stripe", "adyen"]`,
+			"max.items":            "20",
+			"max.items.type":       "number",
+			"max.items.enum":       `[10, 20]`,
+		})
+		defer Reset()
+
+		if actual := GetString("payment.gateway", "stripe"); actual != "adyen" {
+			t.Errorf("Should have returned the value if it was in the enum, actualy returned %v", actual)
+		}
+		if actual := GetNumber("max.items", 10); actual != 20 {
+			t.Errorf("Should have returned the value if it was in the enum, actualy returned %v", actual)
+		}
+		if violations := Violations(); len(violations) != 0 {
+			t.Errorf("Should not have reported any violations, reported %+v", violations)
+		}
+	})
+	t.Run("Should reject numbers and durations out of the min and max bounds", func(t *testing.T) {
+		Mock(map[string]string{
+			"max.items":        "200",
+			"max.items.type":   "integer",
+			"max.items.min":    "1",
+			"max.items.max":    "100",
+			"min.items":        "0",
+			"min.items.type":   "number",
+			"min.items.min":    "1",
+			"timeout":          "1m",
+			"timeout.type":     "duration",
+			"timeout.max":      "30s",
+			"in.bounds":        "50",
+			"in.bounds.type":   "number",
+			"in.bounds.min":    "1",
+			"in.bounds.max":    "100",
+			"string.bound":     "abc",
+			"string.bound.max": "10",
+		})
+		defer Reset()
+
+		if actual := GetInt("max.items", 10); actual != 10 {
+			t.Errorf("Should have returned the default value if the value was greater than max, actualy returned %v", actual)
+		}
+		if actual := GetNumber("min.items", 10); actual != 10 {
+			t.Errorf("Should have returned the default value if the value was lower than min, actualy returned %v", actual)
+		}
+		if actual := GetDuration("timeout", time.Second); actual != time.Second {
+			t.Errorf("Should have returned the default value if the duration was greater than max, actualy returned %v", actual)
+		}
+		if actual := GetNumber("in.bounds", 10); actual != 50 {
+			t.Errorf("Should have returned the value if it was within the bounds, actualy returned %v", actual)
+		}
+		// string.bound is also reported, since bounds are not supported for untyped values
+		if violations := Violations(); len(violations) != 4 {
+			t.Errorf("Should have reported 4 violations, reported %+v", violations)
+		}
+	})
+	t.Run("Should reject values that do not match the pattern", func(t *testing.T) {
+		Mock(map[string]string{
+			"coupon.prefix":         "abc",
+			"coupon.prefix.type":    "string",
+			"coupon.prefix.pattern": "^[A-Z]+$",
+		})
+		defer Reset()
+
+		if actual := GetString("coupon.prefix", "DM"); actual != "DM" {
+			t.Errorf("Should have returned the default value if the value did not match the pattern, actualy returned %v", actual)
+		}
+	})
+	t.Run("Should reject values with invalid metadata", func(t *testing.T) {
+		Mock(map[string]string{
+			"coupon.prefix":         "ABC",
+			"coupon.prefix.type":    "string",
+			"coupon.prefix.pattern": "[A-Z",
+		})
+		defer Reset()
+
+		if actual := GetString("coupon.prefix", "DM"); actual != "DM" {
+			t.Errorf("Should have returned the default value if the pattern was invalid, actualy returned %v", actual)
+		}
+	})
+}
@@ -0,0 +1,193 @@
+package featuretoggle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// the feature_flag OpenTelemetry semantic conventions used in the span events
+const (
+	evaluationEvent  = "feature_flag.evaluation"
+	attrKey          = attribute.Key("feature_flag.key")
+	attrVariant      = attribute.Key("feature_flag.variant")
+	attrProvider     = attribute.Key("feature_flag.provider.name")
+	attrResultValue  = attribute.Key("feature_flag.result.value")
+	attrResultReason = attribute.Key("feature_flag.result.reason")
+	attrErrorType    = attribute.Key("error.type")
+	attrErrorMessage = attribute.Key("error.message")
+	attrMethod       = attribute.Key("feature_flag.method")
+	providerName     = "dm-go-ft"
+)
+
+// contextKey is the type of the keys of the values the library stores in a context
+type contextKey int
+
+const (
+	overridesKey contextKey = iota
+	loggerKey
+)
+
+// overrides represents the feature toggles overridden for a single request (see WithOverrides)
+type overrides struct {
+	// the raw overridden key-value pairs, including the ones of the parent context
+	raw map[string]string
+	// the compiled overridden feature toggles
+	snapshot *snapshot
+}
+
+// WithOverrides returns a copy of the context that overrides the feature toggles in values,
+// for every ctx accessor (like IsEnabledCtx) called with it. Useful to enable a feature toggle for a single request,
+// like for a test customer or an internal header.
+//
+// The values use the same format as the redis hash (and Mock): the "<key>.type" and the other metadata fields
+// may be overridden as well. When the "<key>.type" field is not overridden, the type of the key in redis is used.
+// The overrides of the parent context are kept, unless overridden again.
+func WithOverrides(ctx context.Context, values map[string]string) context.Context {
+	raw := map[string]string{}
+	if o, ok := ctx.Value(overridesKey).(*overrides); ok {
+		for k, v := range o.raw {
+			raw[k] = v
+		}
+	}
+	for k, v := range values {
+		raw[k] = v
+	}
+
+	s := current()
+	for k := range values {
+		typeKey := fmt.Sprintf("%s.type", k)
+		if _, ok := raw[typeKey]; ok || s == nil || strings.HasSuffix(k, ".type") {
+			continue
+		}
+		if t, ok := s.raw[typeKey]; ok {
+			raw[typeKey] = t
+		}
+	}
+
+	return context.WithValue(ctx, overridesKey, &overrides{raw, compile(raw, nil)})
+}
+
+// overridden returns the entry of the key overridden in the context, if any.
+func overridden(ctx context.Context, key string) (*entry, bool) {
+	o, ok := ctx.Value(overridesKey).(*overrides)
+	if !ok {
+		return nil, false
+	}
+
+	e, ok := o.snapshot.entries[key]
+	return e, ok
+}
+
+// ContextWithLogger returns a copy of the context that carries the logger l,
+// used by the ctx accessors (like IsEnabledCtx) instead of the logger configured in Init.
+// Useful to log with a request scoped logger, that already has the request fields (like the trace ID or customer).
+func ContextWithLogger(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// contextLogger returns the logger carried by the context, or the logger configured in Init.
+func contextLogger(ctx context.Context) Logger {
+	if l, ok := ctx.Value(loggerKey).(Logger); ok && l != nil {
+		return l
+	}
+	return logs.Load().l
+}
+
+// evaluated finishes the evaluation of a feature toggle by a ctx accessor: logs the error (if any),
+// adds the evaluation event to the span in the context if it is recording, and emits the EventEvaluation.
+// The value function is called only if the span is recording.
+func evaluated(ctx context.Context, method string, key string, err error, reason string, value func() attribute.Value) {
+	if err != nil {
+		logMiss(ctx, method, key, err)
+	}
+
+	span := trace.SpanFromContext(ctx)
+	recording := span.IsRecording()
+	if !recording && !listening() {
+		return
+	}
+
+	e, isOverride := overridden(ctx, key)
+	if !isOverride {
+		e, _ = current().entry(key)
+	}
+
+	switch {
+	case err != nil:
+		reason = ReasonError
+	case isOverride:
+		reason = ReasonOverride
+	}
+
+	if recording {
+		result, variant := value(), attribute.Value{}
+		if e != nil {
+			// the raw value identifies the variant, even when the result is calculated from it (like IsEnabledByPercent)
+			variant = attribute.StringValue(e.val)
+		}
+		if e != nil && e.typ == "secret" && err == nil {
+			result, variant = attribute.StringValue(Redacted), attribute.StringValue(Redacted)
+		}
+
+		attrs := []attribute.KeyValue{
+			attrKey.String(key),
+			attrProvider.String(providerName),
+			attrMethod.String(method),
+			{Key: attrResultValue, Value: result},
+			attrResultReason.String(reason),
+		}
+		if err != nil {
+			attrs = append(attrs, attrErrorType.String(ErrorType(err)), attrErrorMessage.String(err.Error()))
+		} else if e != nil {
+			attrs = append(attrs, attribute.KeyValue{Key: attrVariant, Value: variant})
+		}
+
+		span.AddEvent(evaluationEvent, trace.WithAttributes(attrs...))
+	}
+
+	if listening() {
+		ev := Event{Type: EventEvaluation, Key: key, Method: method, Reason: reason, Err: err}
+		if e != nil {
+			ev.Value, ev.ValueType = e.redacted(e.val), e.typ
+		}
+		emit(ev)
+	}
+}
+
+// ErrorType returns the error.type of the feature_flag OpenTelemetry semantic conventions for an accessor error,
+// based on its sentinel error (ex.: "flag_not_found" for ErrNotFound).
+func ErrorType(err error) string {
+	switch {
+	case errors.Is(err, ErrNotInitialized):
+		return "provider_not_ready"
+	case errors.Is(err, ErrNotFound):
+		return "flag_not_found"
+	case errors.Is(err, ErrTypeMissing), errors.Is(err, ErrTypeMismatch):
+		return "type_mismatch"
+	case errors.Is(err, ErrParse):
+		return "parse_error"
+	case errors.Is(err, ErrConstraintViolation):
+		return "constraint_violation"
+	}
+
+	return "general"
+}
+
+// anyValue converts a value of any type into a span attribute value.
+func anyValue(v any) attribute.Value {
+	switch v := v.(type) {
+	case string:
+		return attribute.StringValue(v)
+	case bool:
+		return attribute.BoolValue(v)
+	case fmt.Stringer:
+		return attribute.StringValue(v.String())
+	}
+
+	return attribute.StringValue(fmt.Sprintf("%v", v))
+}
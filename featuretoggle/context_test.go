@@ -0,0 +1,113 @@
+package featuretoggle
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestContext(t *testing.T) {
+	toggles := map[string]string{
+		"NewCheckout":      "false",
+		"NewCheckout.type": "boolean",
+		"Gateway":          "stripe",
+		"Gateway.type":     "string",
+	}
+
+	t.Run("Should honour the overrides carried by the context", func(t *testing.T) {
+		Mock(toggles)
+		defer Reset()
+
+		ctx := WithOverrides(context.Background(), map[string]string{"NewCheckout": "true"})
+		ctx = WithOverrides(ctx, map[string]string{"Gateway": "adyen", "Retries": "3", "Retries.type": "integer"})
+
+		if !IsEnabledCtx(ctx, "NewCheckout", false) {
+			t.Errorf("Should have returned the overridden value, using the type of the key in redis")
+		}
+		if actual := GetStringCtx(ctx, "Gateway", ""); actual != "adyen" {
+			t.Errorf("Should have returned the overridden value, returned %v", actual)
+		}
+		if actual := GetIntCtx(ctx, "Retries", 0); actual != 3 {
+			t.Errorf("Should have returned the overridden value of a key that is not in redis, returned %v", actual)
+		}
+		if IsEnabled("NewCheckout", true) {
+			t.Errorf("Should not have used the overrides without the context")
+		}
+		withRegistry(func() {
+			if actual := String("Gateway", "").ValueCtx(ctx); actual != "adyen" {
+				t.Errorf("Should have returned the overridden value of the typed handle, returned %v", actual)
+			}
+		})
+	})
+	t.Run("Should log the misses with the context logger", func(t *testing.T) {
+		Mock(toggles)
+		defer Reset()
+		global := &recordLogger{}
+		setLogger(global, -1)
+
+		scoped := &recordLogger{}
+		ctx := ContextWithLogger(context.Background(), scoped)
+		GetNumberCtx(ctx, "Missing", 0)
+		GetNumber("Missing", 0)
+
+		if len(scoped.msgs) != 1 || len(global.msgs) != 1 {
+			t.Errorf("Should have logged each miss with its own logger, logged %v and %v", scoped.msgs, global.msgs)
+		}
+	})
+	t.Run("Should add the evaluations to the span in the context", func(t *testing.T) {
+		Mock(toggles)
+		defer Reset()
+
+		rec := tracetest.NewSpanRecorder()
+		tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+		ctx, span := tp.Tracer("test").Start(context.Background(), "request")
+		ctx = WithOverrides(ctx, map[string]string{"Gateway": "adyen"})
+
+		IsEnabledCtx(ctx, "NewCheckout", true)
+		GetStringCtx(ctx, "Gateway", "")
+		GetCtx(ctx, "Missing", map[string]int{"daily": 5})
+		span.End()
+
+		events := rec.Ended()[0].Events()
+		if len(events) != 3 {
+			t.Fatalf("Should have added an event for each evaluation, added %+v", events)
+		}
+
+		expected := []map[attribute.Key]string{
+			{attrKey: "NewCheckout", attrResultValue: "false", attrResultReason: ReasonStatic, attrVariant: "false"},
+			{attrKey: "Gateway", attrResultValue: "adyen", attrResultReason: ReasonOverride, attrVariant: "adyen"},
+			{attrKey: "Missing", attrResultValue: "map[daily:5]", attrResultReason: ReasonError, attrErrorType: "flag_not_found"},
+		}
+		for i, e := range events {
+			if e.Name != evaluationEvent {
+				t.Errorf("Should have named the event %s, named %s", evaluationEvent, e.Name)
+			}
+
+			attrs := map[attribute.Key]string{}
+			for _, kv := range e.Attributes {
+				attrs[kv.Key] = kv.Value.Emit()
+			}
+			for k, v := range expected[i] {
+				if attrs[k] != v {
+					t.Errorf("Should have set the attribute %s of the event %d to %s, set %s", k, i, v, attrs[k])
+				}
+			}
+		}
+	})
+	t.Run("Should not allocate when the context has no span", func(t *testing.T) {
+		Mock(toggles)
+		defer Reset()
+		ctx := context.Background()
+
+		allocs := testing.AllocsPerRun(100, func() {
+			IsEnabledCtx(ctx, "NewCheckout", false)
+			GetStringCtx(ctx, "Gateway", "")
+		})
+		if allocs != 0 {
+			t.Errorf("Should not have allocated when reading the toggles, allocated %v times", allocs)
+		}
+	})
+}
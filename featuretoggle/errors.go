@@ -0,0 +1,139 @@
+package featuretoggle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotInitialized means the library was not initiated
+	ErrNotInitialized = errors.New("the library was not initiated")
+	// ErrNotFound means the key was not found, or its value is empty
+	ErrNotFound = errors.New("the value was not found or empty")
+	// ErrTypeMissing means the "<key>.type" field was not found, or is empty
+	ErrTypeMissing = errors.New("the value type was not found or empty")
+	// ErrTypeMismatch means the "<key>.type" field does not match the type expected by the accessor
+	ErrTypeMismatch = errors.New("the value type does not match")
+	// ErrParse means the value could not be parsed into the type expected by the accessor
+	ErrParse = errors.New("the value could not be parsed")
+	// ErrConstraintViolation means the value was rejected by its constraints (see Violations)
+	ErrConstraintViolation = errors.New("the value violates its constraints")
+)
+
+// KeyError describes why a feature toggle value could not be used.
+//
+// It matches one of the sentinel errors (ErrNotInitialized, ErrNotFound, ErrTypeMissing, ErrTypeMismatch,
+// ErrParse or ErrConstraintViolation) with errors.Is, and its cause (like a *Violation) with errors.As.
+type KeyError struct {
+	// the feature toggle key
+	Key string
+	// the sentinel error that describes the problem
+	Kind error
+	// the types expected by the accessor, for ErrTypeMismatch
+	ExpectedTypes []string
+	// the type found in the "<key>.type" field, for ErrTypeMismatch
+	FoundType string
+	// the underlying error, for ErrParse and ErrConstraintViolation
+	Err error
+}
+
+// Error returns the error message
+func (e *KeyError) Error() string {
+	return fmt.Sprintf("feature toggle %s: %s", e.Key, e.reason())
+}
+
+// Unwrap returns the sentinel error and the underlying error, if any
+func (e *KeyError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// reason returns the error message without the key
+func (e *KeyError) reason() string {
+	switch {
+	case e.Kind == ErrTypeMismatch:
+		return fmt.Sprintf("%s, expected %s but found %s", e.Kind, strings.Join(e.ExpectedTypes, " or "), e.FoundType)
+	case e.Err != nil:
+		return fmt.Sprintf("%s: %s", e.Kind, e.Err)
+	}
+
+	return e.Kind.Error()
+}
+
+// lookup finds the entry of the key overridden in the context (see WithOverrides), or in the current snapshot.
+// The "<key>.type" field is required only if requireType is true.
+func lookup(ctx context.Context, key string, requireType bool) (*entry, error) {
+	e, ok := overridden(ctx, key)
+	if !ok {
+		s := current()
+		if s == nil {
+			return nil, &KeyError{Key: key, Kind: ErrNotInitialized}
+		}
+
+		e, ok = s.entry(key)
+	}
+
+	if !ok || e.blank {
+		return nil, &KeyError{Key: key, Kind: ErrNotFound}
+	}
+
+	if requireType && !e.hasType {
+		return nil, &KeyError{Key: key, Kind: ErrTypeMissing}
+	}
+
+	if e.violation != nil {
+		return nil, &KeyError{Key: key, Kind: ErrConstraintViolation, Err: e.violation}
+	}
+
+	return e, nil
+}
+
+// expect checks if the entry has one of the expected types, and if its value was parsed successfully.
+func (e *entry) expect(key string, types ...string) error {
+	for _, t := range types {
+		if e.typ != t {
+			continue
+		}
+
+		if e.err != nil {
+			return &KeyError{Key: key, Kind: ErrParse, Err: e.err}
+		}
+		return nil
+	}
+
+	// copy the expected types, so the variadic slice does not escape to the heap on every call
+	expected := append([]string(nil), types...)
+	return &KeyError{Key: key, Kind: ErrTypeMismatch, ExpectedTypes: expected, FoundType: e.typ}
+}
+
+// logMiss logs why a feature toggle accessor returned the default value.
+// Uses the logger carried by the context, if any (see ContextWithLogger).
+// Each distinct miss reason of a key is logged at most once per log interval (see Config.LogInterval).
+func logMiss(ctx context.Context, method string, key string, err error) {
+	reason := err.Error()
+	kind := reason
+
+	var kerr *KeyError
+	if errors.As(err, &kerr) {
+		reason = kerr.reason()
+		kind = kerr.Kind.Error()
+	}
+
+	ok, suppressed := allowMiss(key, kind, time.Now())
+	if !ok {
+		return
+	}
+
+	msg := fmt.Sprintf("%s for key %s, %s", method, key, reason)
+	l := contextLogger(ctx)
+	if suppressed > 0 {
+		l.InfoContext(ctx, msg, "key", key, "method", method, "suppressed", suppressed)
+		return
+	}
+	l.InfoContext(ctx, msg, "key", key, "method", method)
+}
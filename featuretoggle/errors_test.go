@@ -0,0 +1,84 @@
+package featuretoggle
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStrictAccessors(t *testing.T) {
+	t.Run("Should return ErrNotInitialized if the library was not initiated", func(t *testing.T) {
+		Mock(nil)
+		defer Reset()
+
+		_, err := IsEnabledE("MyKey")
+		if !errors.Is(err, ErrNotInitialized) {
+			t.Errorf("Should have returned ErrNotInitialized, returned %v", err)
+		}
+	})
+	t.Run("Should return the sentinel error that describes the problem", func(t *testing.T) {
+		Mock(map[string]string{
+			"empty":          "",
+			"notype":         "true",
+			"mismatch":       "10",
+			"mismatch.type":  "string",
+			"parse":          "ten",
+			"parse.type":     "number",
+			"violation":      "stirpe",
+			"violation.type": "string",
+			"violation.enum": `["stripe"]`,
+			"json":           `{"daily": "five"}`,
+		})
+		defer Reset()
+
+		cases := []struct {
+			name     string
+			err      error
+			expected error
+		}{
+			{"missing", func() error { _, err := GetStringE("missing"); return err }(), ErrNotFound},
+			{"empty", func() error { _, err := GetStringE("empty"); return err }(), ErrNotFound},
+			{"notype", func() error { _, err := IsEnabledE("notype"); return err }(), ErrTypeMissing},
+			{"mismatch", func() error { _, err := GetNumberE("mismatch"); return err }(), ErrTypeMismatch},
+			{"parse", func() error { _, err := GetNumberE("parse"); return err }(), ErrParse},
+			{"violation", func() error { _, err := GetStringE("violation"); return err }(), ErrConstraintViolation},
+			{"json", func() error { _, err := GetE[map[string]int]("json"); return err }(), ErrParse},
+		}
+		for _, c := range cases {
+			if !errors.Is(c.err, c.expected) {
+				t.Errorf("Should have returned %v for key %s, returned %v", c.expected, c.name, c.err)
+			}
+
+			var kerr *KeyError
+			if !errors.As(c.err, &kerr) || kerr.Key != c.name {
+				t.Errorf("Should have returned a *KeyError for key %s, returned %v", c.name, c.err)
+			}
+		}
+	})
+	t.Run("Should expose the constraint violation with errors.As", func(t *testing.T) {
+		Mock(map[string]string{
+			"violation":      "stirpe",
+			"violation.type": "string",
+			"violation.enum": `["stripe"]`,
+		})
+		defer Reset()
+
+		_, err := GetStringE("violation")
+
+		var v *Violation
+		if !errors.As(err, &v) || v.Constraint != "enum" {
+			t.Errorf("Should have exposed the violation, returned %v", err)
+		}
+	})
+	t.Run("Should return the value and no error for valid toggles", func(t *testing.T) {
+		Mock(map[string]string{
+			"MyKey":      "true",
+			"MyKey.type": "boolean",
+		})
+		defer Reset()
+
+		b, err := IsEnabledE("MyKey")
+		if err != nil || !b {
+			t.Errorf("Should have returned the value, returned %v, %v", b, err)
+		}
+	})
+}
@@ -0,0 +1,136 @@
+package featuretoggle
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventType represents the kind of an Event
+type EventType string
+
+const (
+	// EventConstraintViolation is emitted when a feature toggle value is rejected by its constraints (see Violations)
+	EventConstraintViolation EventType = "constraint_violation"
+	// EventLastKnownGood is emitted when a feature toggle value is invalid,
+	// and the last known good value of the feature toggle is kept instead
+	EventLastKnownGood EventType = "last_known_good"
+	// EventEvaluation is emitted when a feature toggle is evaluated by an accessor that returns a default value
+	// (like IsEnabled), with the Method, ValueType and Reason of the evaluation, and the Err if the default was returned
+	EventEvaluation EventType = "evaluation"
+	// EventCacheRebuilt is emitted when the feature toggles are loaded from redis, with the Duration and Keys of the rebuild
+	EventCacheRebuilt EventType = "cache_rebuilt"
+	// EventCacheRebuildFailed is emitted when the feature toggles could not be loaded from redis,
+	// with the Duration and Err of the rebuild
+	EventCacheRebuildFailed EventType = "cache_rebuild_failed"
+	// EventReconnect is emitted when the subscription to the redis feature toggle updates is reconnected
+	EventReconnect EventType = "reconnect"
+	// EventRedisCommand is emitted after each redis command used to load the feature toggles,
+	// with the Method (the command name, like "HGETALL"), the Duration and the Err of the command
+	EventRedisCommand EventType = "redis_command"
+	// EventStale is emitted when the feature toggles were not confirmed to be up to date for longer than
+	// Config.MaxStaleness (see Status), with the Duration since the last confirmation and the last Err, if any
+	EventStale EventType = "stale"
+	// EventFresh is emitted when the feature toggles are confirmed to be up to date again, after an EventStale
+	EventFresh EventType = "fresh"
+)
+
+// the reasons of the evaluations, following the feature_flag OpenTelemetry semantic conventions
+const (
+	// ReasonStatic means the value was found in redis
+	ReasonStatic = "static"
+	// ReasonSplit means the value was calculated from a percentage found in redis (see IsEnabledByPercent)
+	ReasonSplit = "split"
+	// ReasonOverride means the value was overridden in the context (see WithOverrides)
+	ReasonOverride = "targeting_match"
+	// ReasonError means the value could not be used, and the default value was returned
+	ReasonError = "error"
+)
+
+// Event represents something that happened with the feature toggles, emitted to the listeners registered with OnEvent
+type Event struct {
+	// the kind of the event
+	Type EventType
+	// the feature toggle key, if the event refers to a single feature toggle
+	Key string
+	// the feature toggle value that caused the event, if any (Redacted for the feature toggles of type "secret")
+	Value string
+	// the error that caused the event, if any
+	Err error
+	// the accessor that evaluated the feature toggle for EventEvaluation (ex.: "IsEnabled"),
+	// or the command name for EventRedisCommand (ex.: "HGETALL")
+	Method string
+	// the declared type of the feature toggle, for EventEvaluation, empty if the feature toggle has no type
+	ValueType string
+	// the reason of the evaluation result, for EventEvaluation (like ReasonStatic or ReasonError)
+	Reason string
+	// how long the rebuild took, for EventCacheRebuilt and EventCacheRebuildFailed,
+	// the redis command took, for EventRedisCommand, or since the last confirmation, for EventStale
+	Duration time.Duration
+	// the number of feature toggles loaded, for EventCacheRebuilt
+	Keys int
+	// when the event happened
+	Time time.Time
+}
+
+// listener represents a function registered with OnEvent
+type listener struct {
+	id int
+	fn func(Event)
+}
+
+var (
+	// the listeners registered with OnEvent
+	listeners   []listener
+	listenersMu sync.RWMutex
+	// the id of the last listener registered
+	lastListenerID int
+	// the number of listeners registered, so events are not built when no one listens to them
+	listenerCount atomic.Int32
+)
+
+// OnEvent registers a listener that receives every event emitted by the library.
+// Listeners are called synchronously, so they must be fast and must not block.
+//
+// returns a function that unregisters the listener.
+func OnEvent(fn func(Event)) (unregister func()) {
+	listenersMu.Lock()
+	defer listenersMu.Unlock()
+
+	lastListenerID++
+	id := lastListenerID
+	listeners = append(listeners, listener{id, fn})
+	listenerCount.Add(1)
+
+	return func() {
+		listenersMu.Lock()
+		defer listenersMu.Unlock()
+
+		for i, l := range listeners {
+			if l.id == id {
+				listeners = append(listeners[:i:i], listeners[i+1:]...)
+				listenerCount.Add(-1)
+				return
+			}
+		}
+	}
+}
+
+// listening checks if there is any listener registered.
+func listening() bool {
+	return listenerCount.Load() > 0
+}
+
+// emit sends the event to every registered listener.
+func emit(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	listenersMu.RLock()
+	defer listenersMu.RUnlock()
+
+	for _, l := range listeners {
+		l.fn(e)
+	}
+}
@@ -0,0 +1,204 @@
+package featuretoggle
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// fakeRedis is a redisClient that returns fixed feature toggles
+type fakeRedis struct {
+	toggles map[string]string
+	err     error
+	calls   int
+	hgets   int
+	// the entries of the change stream
+	stream []redis.XMessage
+	// if set, xread returns the errors received from it, in order
+	xreadErrs chan error
+	// if set, hgetall signals entered and waits for release before returning
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (f *fakeRedis) subscribe(pattern string) *redis.PubSub {
+	return nil
+}
+
+func (f *fakeRedis) hgetall(namespace string) (map[string]string, error) {
+	f.calls++
+	if f.entered != nil {
+		f.entered <- struct{}{}
+		<-f.release
+	}
+	return f.toggles, f.err
+}
+
+func (f *fakeRedis) hget(namespace string, field string) (string, error) {
+	f.hgets++
+	return f.toggles[field], f.err
+}
+
+func (f *fakeRedis) xread(stream string, id string, count int64, block time.Duration) ([]redis.XMessage, error) {
+	if f.xreadErrs != nil {
+		return nil, <-f.xreadErrs
+	}
+
+	var msgs []redis.XMessage
+	for _, msg := range f.stream {
+		if msg.ID > id && int64(len(msgs)) < count {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs, f.err
+}
+
+func (f *fakeRedis) lastID(stream string) (string, error) {
+	if len(f.stream) == 0 {
+		return "0", f.err
+	}
+	return f.stream[len(f.stream)-1].ID, f.err
+}
+
+func (f *fakeRedis) ping() error {
+	return f.err
+}
+
+func TestEvents(t *testing.T) {
+	t.Run("Should emit the evaluations", func(t *testing.T) {
+		Mock(map[string]string{
+			"MyBool":         "true",
+			"MyBool.type":    "boolean",
+			"MyPercent":      "100",
+			"MyPercent.type": "integer",
+		})
+		defer Reset()
+
+		var events []Event
+		unregister := OnEvent(func(e Event) {
+			events = append(events, e)
+		})
+		defer unregister()
+
+		IsEnabled("MyBool", false)
+		IsEnabledByPercent("MyPercent")
+		GetString("MyBool", "")
+
+		expected := []Event{
+			{Type: EventEvaluation, Key: "MyBool", Method: "IsEnabled", ValueType: "boolean", Reason: ReasonStatic},
+			{Type: EventEvaluation, Key: "MyPercent", Method: "IsEnabledByPercent", ValueType: "integer", Reason: ReasonSplit},
+			{Type: EventEvaluation, Key: "MyBool", Method: "GetString", ValueType: "boolean", Reason: ReasonError},
+		}
+		if len(events) != len(expected) {
+			t.Fatalf("Should have emitted an event for each evaluation, emitted %+v", events)
+		}
+		for i, e := range expected {
+			actual := events[i]
+			if actual.Type != e.Type || actual.Key != e.Key || actual.Method != e.Method ||
+				actual.ValueType != e.ValueType || actual.Reason != e.Reason {
+				t.Errorf("Expected the event %+v, emitted %+v", e, actual)
+			}
+		}
+		if !errors.Is(events[2].Err, ErrTypeMismatch) {
+			t.Errorf("Should have emitted the evaluation error, emitted %v", events[2].Err)
+		}
+	})
+	t.Run("Should not expose the secret values", func(t *testing.T) {
+		defer Reset()
+		rec := &recordLogger{}
+		setLogger(rec, 0)
+
+		var events []Event
+		unregister := OnEvent(func(e Event) {
+			events = append(events, e)
+		})
+		defer unregister()
+
+		Mock(map[string]string{
+			"MyToken":                 "s3cr3t",
+			"MyToken.type":            "secret",
+			"EnumToken":               "l3ak3d",
+			"EnumToken.type":          "secret",
+			"EnumToken.enum":          `["0th3r"]`,
+			"SchemaToken":             `{"token": "l3ak3d"}`,
+			"SchemaToken.type":        "secret",
+			"SchemaToken.schema":      `{"properties": {"token": {"const": "0th3r"}}}`,
+			"ScheduledToken":          "s3cr3t",
+			"ScheduledToken.type":     "secret",
+			"ScheduledToken.enum":     `["s3cr3t"]`,
+			"ScheduledToken.schedule": scheduleOf("UTC", "2000-01-01T00:00:00", `"l3ak3d"`),
+		})
+		for _, key := range []string{"MyToken", "EnumToken", "SchemaToken", "ScheduledToken"} {
+			GetString(key, "")
+		}
+
+		leaks := func(s string) bool {
+			return strings.Contains(s, "s3cr3t") || strings.Contains(s, "l3ak3d") || strings.Contains(s, "0th3r")
+		}
+		if len(events) == 0 {
+			t.Fatalf("Should have emitted the events")
+		}
+		for _, e := range events {
+			if leaks(e.Value) || (e.Err != nil && leaks(e.Err.Error())) {
+				t.Errorf("Should have redacted the secret values of the event, emitted %+v", e)
+			}
+		}
+		for _, v := range Violations() {
+			if leaks(v.Value) || leaks(v.Error()) {
+				t.Errorf("Should have redacted the secret values of the violation, returned %+v", v)
+			}
+		}
+		for i, msg := range rec.msgs {
+			if leaks(msg) || leaks(fmt.Sprint(rec.args[i]...)) {
+				t.Errorf("Should have redacted the secret values of the logs, logged %s %v", msg, rec.args[i])
+			}
+		}
+	})
+	t.Run("Should emit the cache rebuilds", func(t *testing.T) {
+		defer Reset()
+		fake := &fakeRedis{toggles: map[string]string{
+			"MyBool":      "true",
+			"MyBool.type": "boolean",
+			"MyString":    "value",
+			"Orphan.type": "string",
+		}}
+		client, serviceName = fake, "MyService"
+
+		var events []Event
+		unregister := OnEvent(func(e Event) {
+			events = append(events, e)
+		})
+		defer unregister()
+
+		err := buildCache()
+		if err != nil {
+			t.Fatalf("Should have rebuilt the cache, returned %v", err)
+		}
+
+		fake.err = errors.New("connection refused")
+		err = buildCache()
+		if err == nil {
+			t.Fatalf("Should have failed to rebuild the cache")
+		}
+
+		if len(events) != 4 {
+			t.Fatalf("Should have emitted the redis command and the rebuild events, emitted %+v", events)
+		}
+		if events[0].Type != EventRedisCommand || events[0].Method != "HGETALL" || events[0].Err != nil {
+			t.Errorf("Should have emitted the redis command event, emitted %+v", events[0])
+		}
+		if events[1].Type != EventCacheRebuilt || events[1].Keys != 3 {
+			t.Errorf("Should have emitted the rebuild event, emitted %+v", events[1])
+		}
+		if events[2].Type != EventRedisCommand || events[2].Err != fake.err {
+			t.Errorf("Should have emitted the failed redis command event, emitted %+v", events[2])
+		}
+		if events[3].Type != EventCacheRebuildFailed {
+			t.Errorf("Should have emitted the failed rebuild event, emitted %+v", events[3])
+		}
+	})
+}
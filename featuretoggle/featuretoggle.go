@@ -1,22 +1,23 @@
 package featuretoggle
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/rand"
+	"net"
 	"reflect"
-	"strconv"
 	"strings"
+	"time"
 
-	"github.com/delivery-much/dm-go/logger"
 	"github.com/go-redis/redis"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var (
 	// the redis client connection
 	client redisClient
-	// represents all of the service feature toggles (key-value pairs) saved in memory
-	localMemory map[string]string
 	// the name of the service currently being used
 	serviceName string
 )
@@ -24,17 +25,29 @@ var (
 // Mock mocks the feature toggle library, will use the keys provided as a param when acessing the feature toggles.
 // Used for testing
 func Mock(keys map[string]string) {
-	localMemory = keys
+	store(keys)
 }
 
 // Reset resets the mock library to its empty state.
 func Reset() {
 	client = nil
-	localMemory = map[string]string{}
 	serviceName = ""
+	resetStatus()
+	setLogger(nil, 0)
+	keepLastKnownGood.Store(false)
+	versioned.Store(false)
+	if r := rebuilds.Swap(nil); r != nil {
+		r.stop()
+	}
+	resetBindings()
+	store(map[string]string{})
+	resetChanges()
 }
 
-// Init inits the feature toggle library
+// Init inits the feature toggle library.
+//
+// If the config is Strict, also validates every declared flag after loading the feature toggles,
+// and returns the validation errors. The library is left initiated even if the validation fails.
 func Init(c Config) error {
 	cl, err := getRedisClient(c.Host, c.Port, c.DB)
 	if err != nil {
@@ -42,69 +55,160 @@ func Init(c Config) error {
 	}
 	client = cl
 	serviceName = c.ServiceName
+	resetStatus()
+	setLogger(c.Logger, c.LogInterval)
+	keepLastKnownGood.Store(c.LastKnownGood)
+	versioned.Store(c.Versioned)
+	if r := rebuilds.Swap(newRebuilder(c.RebuildDebounce, rebuild)); r != nil {
+		r.stop()
+	}
 
-	// subscribe to the feature toggle channel and wait for changes
-	channelPattern := fmt.Sprintf("__keyspace@%d__:*", c.DB)
-	sub := cl.subscribe(channelPattern)
-	if sub == nil {
-		return fmt.Errorf("Failed to subscribe to feature toggle channel")
+	if c.ChangeStream {
+		// the changes after the last entry are read after loading the feature toggles, so that none is missed
+		id, err := cl.lastID(ChangeStream(c.ServiceName))
+		if err != nil {
+			id = "0"
+		}
+		go consumeChanges(cl, id)
+	} else {
+		// subscribe to the feature toggle channel and wait for changes
+		channelPattern := fmt.Sprintf("__keyspace@%d__:*", c.DB)
+		sub := cl.subscribe(channelPattern)
+		if sub == nil {
+			return fmt.Errorf("Failed to subscribe to feature toggle channel")
+		}
+		go waitForUpdates(sub, channelPattern)
+	}
+	if c.MaxStaleness > 0 {
+		watchStaleness(c.MaxStaleness)
 	}
-	go waitForUpdates(sub)
 
 	err = buildCache()
 	if err != nil {
 		return err
 	}
 
-	logger.NoCTX().Infof("Redis feature toggle started for service %s", c.ServiceName)
+	logInfo(context.Background(), "Redis feature toggle started", "service", c.ServiceName)
+
+	if c.Strict {
+		return Validate()
+	}
 	return nil
 }
 
+// subscriptionHealthInterval is how long the subscription waits for a message before checking its connection
+const subscriptionHealthInterval = 30 * time.Second
+
 // waitForUpdates receives a redis message subscriber,
 // and waits forever for any updates on the redis cache for the specified service.
 // When an update is received, rebuilds de cache.
-func waitForUpdates(sub *redis.PubSub) {
-	ch := sub.Channel()
+//
+// The subscription is reconnected (and the cache rebuilt, since updates may have been missed)
+// when redis does not answer a ping in time.
+func waitForUpdates(sub *redis.PubSub, pattern string) {
+	subscribed := false
+	pinged := false
 
 	for {
-		select {
-		case msg := <-ch:
-			if msg == nil {
-				logger.NoCTX().Info("Received a message via the feature toggle redis subscriber, but it was empty")
+		msg, err := sub.ReceiveTimeout(subscriptionHealthInterval)
+		if err != nil {
+			var netErr net.Error
+			switch {
+			case err.Error() == "redis: client is closed":
+				logInfo(context.Background(), "The feature toggle redis subscriber was closed")
+				subscriptionChanged(false, nil)
 				return
+			case errors.As(err, &netErr) && netErr.Timeout() && !pinged:
+				pinged = true
+				_ = sub.Ping()
+			case errors.As(err, &netErr) && netErr.Timeout():
+				// redis did not answer the ping, so the connection is probably dead
+				subscriptionChanged(false, err)
+				sub.Close()
+				sub = client.subscribe(pattern)
+				subscribed, pinged = false, false
+				reconnected(err)
+			default:
+				subscriptionChanged(false, err)
+				time.Sleep(time.Second)
 			}
-
-			separatedChannelName := strings.Split(msg.Channel, ":")
-			if len(separatedChannelName) < 2 {
-				logger.NoCTX().Infof(
-					"Failed to process the feature toggle update message, the channel name was in a unexpected format (%s)",
-					msg.Channel,
-				)
-				return
-			}
-
-			channelID := separatedChannelName[1]
-			if msg.Payload == "hset" && channelID == serviceName {
-				err := buildCache()
-				if err != nil {
-					logger.NoCTX().Infof("Failed to rebuild feature toggle redis with message: %s", err.Error())
-					return
-				}
-				logger.NoCTX().Infof("Redis feature toggle rebuilt for %s", serviceName)
+			continue
+		}
+		pinged = false
+		// any message (including the ping answer) means the subscription is alive
+		subscriptionChanged(true, nil)
+
+		switch msg := msg.(type) {
+		case *redis.Subscription:
+			// the subscription is repeated whenever the redis client reconnects
+			if subscribed {
+				reconnected(nil)
 			}
+			subscribed = true
+		case *redis.Message:
+			handleUpdate(msg)
 		}
 	}
 }
 
+// handleUpdate rebuilds the cache, if the message is an update of the feature toggles of the service.
+func handleUpdate(msg *redis.Message) {
+	separatedChannelName := strings.Split(msg.Channel, ":")
+	if len(separatedChannelName) < 2 {
+		logInfo(context.Background(),
+			"Failed to process the feature toggle update message, the channel name was in a unexpected format",
+			"channel", msg.Channel,
+		)
+		return
+	}
+
+	channelID := separatedChannelName[1]
+	if msg.Payload == "hset" && channelID == serviceName {
+		requestRebuild()
+	}
+}
+
+// reconnected emits the EventReconnect, and rebuilds the cache with the updates that may have been missed.
+func reconnected(reason error) {
+	logInfo(context.Background(), "Redis feature toggle subscription reconnected", "service", serviceName)
+	emit(Event{Type: EventReconnect, Err: reason})
+	requestRebuild()
+}
+
+// rebuild rebuilds the cache, logging the result.
+// In versioned mode, the cache is rebuilt only if the version of the feature toggles changed.
+func rebuild() {
+	if versioned.Load() && !versionChanged() {
+		return
+	}
+
+	err := buildCache()
+	if err != nil {
+		logError(context.Background(), "Failed to rebuild feature toggle redis", "error", err.Error())
+		return
+	}
+	logInfo(context.Background(), "Redis feature toggle rebuilt", "service", serviceName)
+}
+
 // buildCache gets all the feature toggles for the specified service,
 // and saves it to the local memory, so that the toggles can be accessed faster.
+//
+// emits an EventCacheRebuilt, or an EventCacheRebuildFailed if the toggles could not be loaded.
 func buildCache() error {
+	start := time.Now()
+
 	toggles, err := client.hgetall(serviceName)
+	emit(Event{Type: EventRedisCommand, Key: serviceName, Method: "HGETALL", Duration: time.Since(start), Err: err})
 	if err != nil {
-		return fmt.Errorf("Failed to get toggles for service %s: %s", serviceName, err.Error())
+		err = fmt.Errorf("Failed to get toggles for service %s: %s", serviceName, err.Error())
+		synced(err)
+		emit(Event{Type: EventCacheRebuildFailed, Err: err, Duration: time.Since(start)})
+		return err
 	}
 
-	localMemory = toggles
+	store(toggles)
+	synced(nil)
+	emit(Event{Type: EventCacheRebuilt, Duration: time.Since(start), Keys: current().keys()})
 	return nil
 }
 
@@ -118,36 +222,49 @@ func buildCache() error {
 //
 // - the key value is empty;
 //
-// - the key value is not a boolean.
-func IsEnabled(key string, defaultVal bool) (b bool) {
-	if localMemory == nil {
-		logger.NoCTX().Infof("IsEnabled for key %s, the library was not initiated", key)
-		return defaultVal
-	}
+// - the key value is not a boolean;
+//
+// - the key value violates its constraints (see Violations).
+func IsEnabled(key string, defaultVal bool) bool {
+	return IsEnabledCtx(context.Background(), key, defaultVal)
+}
 
-	val, ok := localMemory[key]
-	if !ok || strings.TrimSpace(val) == "" {
-		logger.NoCTX().Infof("IsEnabled for key %s, the value was not found or empty", key)
-		return defaultVal
+// IsEnabledCtx is the same as IsEnabled, but honours the overrides, the logger and the span carried by the context
+// (see WithOverrides and ContextWithLogger).
+func IsEnabledCtx(ctx context.Context, key string, defaultVal bool) bool {
+	b, err := isEnabled(ctx, key)
+	if err != nil {
+		b = defaultVal
 	}
 
-	typeKey := fmt.Sprintf("%s.type", key)
-	t, ok := localMemory[typeKey]
-	if !ok || strings.TrimSpace(t) == "" {
-		logger.NoCTX().Infof("IsEnabled for key %s, the value type was not found or empty", key)
-		return defaultVal
+	evaluated(ctx, "IsEnabled", key, err, ReasonStatic, func() attribute.Value { return attribute.BoolValue(b) })
+	return b
+}
+
+// IsEnabledE checks if given feature key is enabled in redis DB.
+//
+// returns a *KeyError if the value can not be used, for the same reasons IsEnabled returns the default value.
+func IsEnabledE(key string) (bool, error) {
+	return isEnabled(context.Background(), key)
+}
+
+// isEnabled returns the value of the key, or the reason it can not be used.
+func isEnabled(ctx context.Context, key string) (bool, error) {
+	e, err := lookup(ctx, key, true)
+	if err != nil {
+		return false, err
 	}
 
-	b, err := strconv.ParseBool(val)
-	if err != nil || t != "boolean" {
-		logger.NoCTX().Infof("IsEnabled for key %s, the value was not a boolean", key)
-		return defaultVal
+	err = e.expect(key, "boolean")
+	if err != nil {
+		return false, err
 	}
 
-	return
+	return e.boolean, nil
 }
 
-// GetString returns the string value for the given key.
+// GetString returns the string value for the given key, of type "string" or "secret".
+// Secret values are redacted by the debug handler and the evaluation span events.
 //
 // returns the default value if:
 //
@@ -155,32 +272,45 @@ func IsEnabled(key string, defaultVal bool) (b bool) {
 //
 // - the key was not found;
 //
-// - the key value is empty.
+// - the key value is empty;
+//
+// - the key value violates its constraints (see Violations).
 func GetString(key string, defaultVal string) string {
-	if localMemory == nil {
-		logger.NoCTX().Infof("GetString for key %s, the library was not initiated", key)
-		return defaultVal
-	}
+	return GetStringCtx(context.Background(), key, defaultVal)
+}
 
-	val, ok := localMemory[key]
-	if !ok || strings.TrimSpace(val) == "" {
-		logger.NoCTX().Infof("GetString for key %s, the value was not found or empty", key)
-		return defaultVal
+// GetStringCtx is the same as GetString, but honours the overrides, the logger and the span carried by the context
+// (see WithOverrides and ContextWithLogger).
+func GetStringCtx(ctx context.Context, key string, defaultVal string) string {
+	val, err := getString(ctx, key)
+	if err != nil {
+		val = defaultVal
 	}
 
-	typeKey := fmt.Sprintf("%s.type", key)
-	t, ok := localMemory[typeKey]
-	if !ok || strings.TrimSpace(t) == "" {
-		logger.NoCTX().Infof("GetString for key %s, the value type was not found or empty", key)
-		return defaultVal
+	evaluated(ctx, "GetString", key, err, ReasonStatic, func() attribute.Value { return attribute.StringValue(val) })
+	return val
+}
+
+// GetStringE returns the string value for the given key.
+//
+// returns a *KeyError if the value can not be used, for the same reasons GetString returns the default value.
+func GetStringE(key string) (string, error) {
+	return getString(context.Background(), key)
+}
+
+// getString returns the value of the key, or the reason it can not be used.
+func getString(ctx context.Context, key string) (string, error) {
+	e, err := lookup(ctx, key, true)
+	if err != nil {
+		return "", err
 	}
 
-	if t != "string" {
-		logger.NoCTX().Infof("GetString for key %s, the value was not a string", key)
-		return defaultVal
+	err = e.expect(key, "string", "secret")
+	if err != nil {
+		return "", err
 	}
 
-	return val
+	return e.val, nil
 }
 
 // GetNumber returns the number value for the given key.
@@ -193,35 +323,261 @@ func GetString(key string, defaultVal string) string {
 //
 // - the key value is empty;
 //
-// - the key value is not a boolean.
+// - the key value is not a number;
+//
+// - the key value violates its constraints (see Violations).
 func GetNumber(key string, defaultVal float64) float64 {
-	if localMemory == nil {
-		logger.NoCTX().Infof("GetNumber for key %s, the library was not initiated", key)
-		return defaultVal
+	return GetNumberCtx(context.Background(), key, defaultVal)
+}
+
+// GetNumberCtx is the same as GetNumber, but honours the overrides, the logger and the span carried by the context
+// (see WithOverrides and ContextWithLogger).
+func GetNumberCtx(ctx context.Context, key string, defaultVal float64) float64 {
+	n, err := getNumber(ctx, key)
+	if err != nil {
+		n = defaultVal
 	}
 
-	val, ok := localMemory[key]
-	if !ok || strings.TrimSpace(val) == "" {
-		logger.NoCTX().Infof("GetNumber for key %s, the value was not found or empty", key)
-		return defaultVal
+	evaluated(ctx, "GetNumber", key, err, ReasonStatic, func() attribute.Value { return attribute.Float64Value(n) })
+	return n
+}
+
+// GetNumberE returns the number value for the given key.
+//
+// returns a *KeyError if the value can not be used, for the same reasons GetNumber returns the default value.
+func GetNumberE(key string) (float64, error) {
+	return getNumber(context.Background(), key)
+}
+
+// getNumber returns the value of the key, or the reason it can not be used.
+func getNumber(ctx context.Context, key string) (float64, error) {
+	e, err := lookup(ctx, key, true)
+	if err != nil {
+		return 0, err
 	}
 
-	typeKey := fmt.Sprintf("%s.type", key)
-	t, ok := localMemory[typeKey]
-	if !ok || strings.TrimSpace(t) == "" {
-		logger.NoCTX().Infof("GetNumber for key %s, the value type was not found or empty", key)
-		return defaultVal
+	err = e.expect(key, "number", "integer")
+	if err != nil {
+		return 0, err
 	}
 
-	n, err := strconv.ParseFloat(val, 64)
-	if err != nil || t != "number" {
-		logger.NoCTX().Infof("GetNumber for key %s, the value is not a valid number", key)
-		return defaultVal
+	return e.number, nil
+}
+
+// GetInt returns the integer value for the given key.
+//
+// returns the default value if:
+//
+// - the library was not initiated;
+//
+// - the key was not found;
+//
+// - the key value is empty;
+//
+// - the key value is not a valid integer (type "integer" or "number");
+//
+// - the key value violates its constraints (see Violations).
+func GetInt(key string, defaultVal int64) int64 {
+	return GetIntCtx(context.Background(), key, defaultVal)
+}
+
+// GetIntCtx is the same as GetInt, but honours the overrides, the logger and the span carried by the context
+// (see WithOverrides and ContextWithLogger).
+func GetIntCtx(ctx context.Context, key string, defaultVal int64) int64 {
+	n, err := getInt(ctx, key)
+	if err != nil {
+		n = defaultVal
 	}
 
+	evaluated(ctx, "GetInt", key, err, ReasonStatic, func() attribute.Value { return attribute.Int64Value(n) })
 	return n
 }
 
+// GetIntE returns the integer value for the given key.
+//
+// returns a *KeyError if the value can not be used, for the same reasons GetInt returns the default value.
+func GetIntE(key string) (int64, error) {
+	return getInt(context.Background(), key)
+}
+
+// getInt returns the value of the key, or the reason it can not be used.
+func getInt(ctx context.Context, key string) (int64, error) {
+	e, err := lookup(ctx, key, true)
+	if err != nil {
+		return 0, err
+	}
+
+	err = e.expect(key, "integer", "number")
+	if err != nil {
+		return 0, err
+	}
+
+	if !e.intOK {
+		return 0, &KeyError{Key: key, Kind: ErrParse, Err: fmt.Errorf("%s is not an integer", e.val)}
+	}
+
+	return e.integer, nil
+}
+
+// GetDuration returns the duration value for the given key.
+//
+// returns the default value if:
+//
+// - the library was not initiated;
+//
+// - the key was not found;
+//
+// - the key value is empty;
+//
+// - the key value is not a valid duration (type "duration", ex.: "1m30s");
+//
+// - the key value violates its constraints (see Violations).
+func GetDuration(key string, defaultVal time.Duration) time.Duration {
+	return GetDurationCtx(context.Background(), key, defaultVal)
+}
+
+// GetDurationCtx is the same as GetDuration, but honours the overrides, the logger and the span carried by the context
+// (see WithOverrides and ContextWithLogger).
+func GetDurationCtx(ctx context.Context, key string, defaultVal time.Duration) time.Duration {
+	d, err := getDuration(ctx, key)
+	if err != nil {
+		d = defaultVal
+	}
+
+	evaluated(ctx, "GetDuration", key, err, ReasonStatic, func() attribute.Value { return attribute.StringValue(d.String()) })
+	return d
+}
+
+// GetDurationE returns the duration value for the given key.
+//
+// returns a *KeyError if the value can not be used, for the same reasons GetDuration returns the default value.
+func GetDurationE(key string) (time.Duration, error) {
+	return getDuration(context.Background(), key)
+}
+
+// getDuration returns the value of the key, or the reason it can not be used.
+func getDuration(ctx context.Context, key string) (time.Duration, error) {
+	e, err := lookup(ctx, key, true)
+	if err != nil {
+		return 0, err
+	}
+
+	err = e.expect(key, "duration")
+	if err != nil {
+		return 0, err
+	}
+
+	return e.duration, nil
+}
+
+// GetTime returns the datetime value for the given key.
+//
+// returns the default value if:
+//
+// - the library was not initiated;
+//
+// - the key was not found;
+//
+// - the key value is empty;
+//
+// - the key value is not a valid datetime (type "datetime", in RFC3339 format);
+//
+// - the key value violates its constraints (see Violations).
+func GetTime(key string, defaultVal time.Time) time.Time {
+	return GetTimeCtx(context.Background(), key, defaultVal)
+}
+
+// GetTimeCtx is the same as GetTime, but honours the overrides, the logger and the span carried by the context
+// (see WithOverrides and ContextWithLogger).
+func GetTimeCtx(ctx context.Context, key string, defaultVal time.Time) time.Time {
+	t, err := getTime(ctx, key)
+	if err != nil {
+		t = defaultVal
+	}
+
+	evaluated(ctx, "GetTime", key, err, ReasonStatic, func() attribute.Value { return attribute.StringValue(t.Format(time.RFC3339)) })
+	return t
+}
+
+// GetTimeE returns the datetime value for the given key.
+//
+// returns a *KeyError if the value can not be used, for the same reasons GetTime returns the default value.
+func GetTimeE(key string) (time.Time, error) {
+	return getTime(context.Background(), key)
+}
+
+// getTime returns the value of the key, or the reason it can not be used.
+func getTime(ctx context.Context, key string) (time.Time, error) {
+	e, err := lookup(ctx, key, true)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	err = e.expect(key, "datetime")
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return e.time, nil
+}
+
+// GetStringSlice returns the list value for the given key.
+// The returned slice is shared between callers and must be treated as read only.
+//
+// returns the default value if:
+//
+// - the library was not initiated;
+//
+// - the key was not found;
+//
+// - the key value is empty;
+//
+// - the key value is not a valid list of strings (type "list", a json array);
+//
+// - the key value violates its constraints (see Violations).
+func GetStringSlice(key string, defaultVal []string) []string {
+	return GetStringSliceCtx(context.Background(), key, defaultVal)
+}
+
+// GetStringSliceCtx is the same as GetStringSlice, but honours the overrides, the logger and the span carried by the context
+// (see WithOverrides and ContextWithLogger).
+func GetStringSliceCtx(ctx context.Context, key string, defaultVal []string) []string {
+	list, err := getStringSlice(ctx, key)
+	if err != nil {
+		list = defaultVal
+	}
+
+	evaluated(ctx, "GetStringSlice", key, err, ReasonStatic, func() attribute.Value { return attribute.StringSliceValue(list) })
+	return list
+}
+
+// GetStringSliceE returns the list value for the given key.
+// The returned slice is shared between callers and must be treated as read only.
+//
+// returns a *KeyError if the value can not be used, for the same reasons GetStringSlice returns the default value.
+func GetStringSliceE(key string) ([]string, error) {
+	return getStringSlice(context.Background(), key)
+}
+
+// getStringSlice returns the value of the key, or the reason it can not be used.
+func getStringSlice(ctx context.Context, key string) ([]string, error) {
+	e, err := lookup(ctx, key, true)
+	if err != nil {
+		return nil, err
+	}
+
+	err = e.expect(key, "list")
+	if err != nil {
+		return nil, err
+	}
+
+	if !e.listOK {
+		return nil, &KeyError{Key: key, Kind: ErrParse, Err: fmt.Errorf("the list has non string items")}
+	}
+
+	return e.list, nil
+}
+
 // IsEnabledByPercent checks the redis key value for a percentage number (between 0 and 100),
 // calculates an random number (also between 0 and 100), and returns true or false depending whether
 // the calculated number is within the found percentage.
@@ -236,39 +592,28 @@ func GetNumber(key string, defaultVal float64) float64 {
 //
 // - the key value is not a percentage (number between 0 and 100);
 //
+// - the key value violates its constraints (see Violations);
+//
 // - the random number greater than the found percentage.
 func IsEnabledByPercent(key string) bool {
-	if localMemory == nil {
-		logger.NoCTX().Infof("IsEnabledByPercent for key %s, the library was not initiated", key)
-		return false
-	}
-
-	val, ok := localMemory[key]
-	if !ok || strings.TrimSpace(val) == "" {
-		logger.NoCTX().Infof("IsEnabledByPercent for key %s, the value was not found or empty", key)
-		return false
-	}
+	return IsEnabledByPercentCtx(context.Background(), key)
+}
 
-	typeKey := fmt.Sprintf("%s.type", key)
-	t, ok := localMemory[typeKey]
-	if !ok || strings.TrimSpace(t) == "" {
-		logger.NoCTX().Infof("IsEnabledByPercent for key %s, the value type was not found or empty", key)
-		return false
-	}
+// IsEnabledByPercentCtx is the same as IsEnabledByPercent, but honours the overrides, the logger and the span
+// carried by the context (see WithOverrides and ContextWithLogger).
+func IsEnabledByPercentCtx(ctx context.Context, key string) bool {
+	enabled := false
 
-	n, err := strconv.Atoi(val)
-	if err != nil || t != "number" {
-		logger.NoCTX().Infof("IsEnabledByPercent for key %s, the value is not a number", key)
-		return false
+	n, err := getInt(ctx, key)
+	if err == nil && (n > 100 || n < 0) {
+		err = &KeyError{Key: key, Kind: ErrParse, Err: fmt.Errorf("%d is not in percentage format", n)}
 	}
-
-	if n > 100 || n < 0 {
-		logger.NoCTX().Infof("IsEnabledByPercent for key %s, the value is not in percentage format", key)
-		return false
+	if err == nil {
+		enabled = rand.Int63n(100) <= n
 	}
 
-	r := rand.Intn(100)
-	return r <= n
+	evaluated(ctx, "IsEnabledByPercent", key, err, ReasonSplit, func() attribute.Value { return attribute.BoolValue(enabled) })
+	return enabled
 }
 
 /*
@@ -276,6 +621,13 @@ Get gets a feature toggle by the key, but then parses that feature toggle value
 
 If the provided type (T) is string, the raw value from the feature toggle will be returned.
 
+The declared type of the feature toggle is honored when it is not json compatible:
+"duration" values are parsed into time.Duration, "datetime" values into time.Time,
+and "boolean" values into bool using the same rules as IsEnabled.
+
+The decoded value is memoised until the feature toggles are updated, unless the type (T) holds references
+(maps, slices, pointers, etc.), that are decoded again on every call so a caller never changes the value of another.
+
 returns the default value if:
 
 - the library was not initiated;
@@ -284,44 +636,93 @@ returns the default value if:
 
 - the key value is empty.
 
-- the value stored in the key could not be parsed into the provided type (T)
+- the value stored in the key could not be parsed into the provided type (T);
+
+- the key value violates its constraints (see Violations).
 */
-func Get[T any](key string, defaultVal T) (res T) {
-	if localMemory == nil {
-		logger.NoCTX().Infow("[Feature Toggle] The library was not initiated",
-			"key", key,
-			"method", "Get",
-		)
-		return defaultVal
+func Get[T any](key string, defaultVal T) T {
+	return GetCtx(context.Background(), key, defaultVal)
+}
+
+// GetCtx is the same as Get, but honours the overrides, the logger and the span carried by the context
+// (see WithOverrides and ContextWithLogger).
+func GetCtx[T any](ctx context.Context, key string, defaultVal T) T {
+	res, err := getE[T](ctx, key)
+	if err != nil {
+		res = defaultVal
 	}
 
-	val, ok := localMemory[key]
-	if !ok || strings.TrimSpace(val) == "" {
-		logger.NoCTX().Infow("[Feature Toggle] The value was not found",
-			"key", key,
-			"method", "Get",
-		)
-		return defaultVal
+	evaluated(ctx, "Get", key, err, ReasonStatic, func() attribute.Value { return anyValue(res) })
+	return res
+}
+
+// GetE gets a feature toggle by the key, and parses it into the provided type (T), the same way Get does.
+//
+// returns a *KeyError if the value can not be used, for the same reasons Get returns the default value.
+func GetE[T any](key string) (T, error) {
+	return getE[T](context.Background(), key)
+}
+
+// getE returns the value of the key parsed into the type T, or the reason it can not be used.
+func getE[T any](ctx context.Context, key string) (res T, err error) {
+	e, err := lookup(ctx, key, false)
+	if err != nil {
+		return
+	}
+
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	var dec decoded
+	if d, ok := e.decoded.Load(t); ok {
+		dec = d.(decoded)
+	} else {
+		dec = decode[T](e)
+		if memoisable(t) {
+			e.decoded.Store(t, dec)
+		}
 	}
 
-	resPointer := new(T)
-	if reflect.TypeOf(*resPointer).Kind() == reflect.String {
-		res = any(val).(T)
+	if dec.err != nil {
+		err = &KeyError{Key: key, Kind: ErrParse, Err: fmt.Errorf("failed to parse the value to a %s value: %w", t, dec.err)}
 		return
 	}
 
-	err := json.Unmarshal([]byte(val), resPointer)
-	if err != nil {
-		title := fmt.Sprintf("[Feature Toggle] Failed to parse the remote config value to a %T value", res)
-		logger.NoCTX().Infow(title,
-			"key", key,
-			"method", "Get",
-			"error", err.Error(),
-		)
+	return dec.val.(T), nil
+}
 
-		return defaultVal
+// decode parses the feature toggle value into the type T, according to its declared type.
+// string types receive the raw value, every other type is decoded as json.
+func decode[T any](e *entry) decoded {
+	var res T
+
+	v := reflect.ValueOf(&res).Elem()
+	switch {
+	case v.Kind() == reflect.String:
+		v.SetString(e.val)
+		return decoded{val: res}
+	case e.typ == "duration" && v.Type() == durationType:
+		if e.err != nil {
+			return decoded{err: e.err}
+		}
+		v.SetInt(int64(e.duration))
+		return decoded{val: res}
+	case e.typ == "datetime" && v.Type() == timeType:
+		if e.err != nil {
+			return decoded{err: e.err}
+		}
+		v.Set(reflect.ValueOf(e.time))
+		return decoded{val: res}
+	case e.typ == "boolean" && v.Kind() == reflect.Bool:
+		if e.err != nil {
+			return decoded{err: e.err}
+		}
+		v.SetBool(e.boolean)
+		return decoded{val: res}
+	}
+
+	err := json.Unmarshal([]byte(e.val), &res)
+	if err != nil {
+		return decoded{err: err}
 	}
 
-	res = *resPointer
-	return
+	return decoded{val: res}
 }
@@ -3,6 +3,7 @@ package featuretoggle
 import (
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestIsEnabled(t *testing.T) {
@@ -18,9 +19,9 @@ func TestIsEnabled(t *testing.T) {
 		}
 	})
 	t.Run("Should return the default value if the key value is empty", func(t *testing.T) {
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			"MyKey": "",
-		}
+		})
 		defaultVal := true
 
 		actual := IsEnabled("MyKey", defaultVal)
@@ -31,7 +32,7 @@ func TestIsEnabled(t *testing.T) {
 			)
 		}
 
-		localMemory = map[string]string{}
+		Mock(map[string]string{})
 
 		actual = IsEnabled("MyKey", defaultVal)
 		if actual != defaultVal {
@@ -42,10 +43,10 @@ func TestIsEnabled(t *testing.T) {
 		}
 	})
 	t.Run("Should return the default value if the key type is empty", func(t *testing.T) {
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			"MyKey":      "1",
 			"MyKey.type": "",
-		}
+		})
 		defaultVal := true
 
 		actual := IsEnabled("MyKey", defaultVal)
@@ -56,9 +57,9 @@ func TestIsEnabled(t *testing.T) {
 			)
 		}
 
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			"MyKey": "1",
-		}
+		})
 
 		actual = IsEnabled("MyKey", defaultVal)
 		if actual != defaultVal {
@@ -69,10 +70,10 @@ func TestIsEnabled(t *testing.T) {
 		}
 	})
 	t.Run("Should return the default value if the key type is not 'boolean'", func(t *testing.T) {
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			"MyKey":      "1",
 			"MyKey.type": "not boolean",
-		}
+		})
 		defaultVal := true
 
 		actual := IsEnabled("MyKey", defaultVal)
@@ -84,10 +85,10 @@ func TestIsEnabled(t *testing.T) {
 		}
 	})
 	t.Run("Should return the default value if the key value is not a valid boolean", func(t *testing.T) {
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			"MyKey":      "not a boolean value",
 			"MyKey.type": "boolean",
-		}
+		})
 
 		defaultVal := true
 		actual := IsEnabled("MyKey", defaultVal)
@@ -99,10 +100,10 @@ func TestIsEnabled(t *testing.T) {
 		}
 	})
 	t.Run("Should return the found value if the key represents a valid boolean", func(t *testing.T) {
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			"MyKey":      "0",
 			"MyKey.type": "boolean",
-		}
+		})
 
 		expected := false
 		actual := IsEnabled("MyKey", true)
@@ -117,7 +118,7 @@ func TestIsEnabled(t *testing.T) {
 
 func TestGetString(t *testing.T) {
 	t.Run("Should return the default value if the local memory is empty", func(t *testing.T) {
-		localMemory = nil
+		Mock(nil)
 		defaultVal := "MyDefaultVal"
 		actual := GetString("MyKey", defaultVal)
 
@@ -129,7 +130,7 @@ func TestGetString(t *testing.T) {
 		}
 	})
 	t.Run("Should return the default value if the key value is empty", func(t *testing.T) {
-		localMemory = map[string]string{}
+		Mock(map[string]string{})
 		defaultVal := "MyDefaultVal"
 
 		actual := GetString("MyKey", defaultVal)
@@ -140,9 +141,9 @@ func TestGetString(t *testing.T) {
 			)
 		}
 
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			"MyKey": "",
-		}
+		})
 
 		actual = GetString("MyKey", defaultVal)
 		if actual != defaultVal {
@@ -153,9 +154,9 @@ func TestGetString(t *testing.T) {
 		}
 	})
 	t.Run("Should return the default value if the key type is empty", func(t *testing.T) {
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			"MyKey": "myval",
-		}
+		})
 		defaultVal := "MyDefaultVal"
 
 		actual := GetString("MyKey", defaultVal)
@@ -166,10 +167,10 @@ func TestGetString(t *testing.T) {
 			)
 		}
 
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			"MyKey":      "myval",
 			"MyKey.type": "",
-		}
+		})
 
 		actual = GetString("MyKey", defaultVal)
 		if actual != defaultVal {
@@ -180,10 +181,10 @@ func TestGetString(t *testing.T) {
 		}
 	})
 	t.Run("Should return the default value if the key type is not 'string'", func(t *testing.T) {
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			"MyKey":      "myval",
 			"MyKey.type": "not a string",
-		}
+		})
 
 		defaultVal := "MyDefaultVal"
 		actual := GetString("MyKey", defaultVal)
@@ -196,10 +197,10 @@ func TestGetString(t *testing.T) {
 	})
 	t.Run("Should return the found value if the key represents a valid string", func(t *testing.T) {
 		expected := "MyReturn"
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			"MyKey":      expected,
 			"MyKey.type": "string",
-		}
+		})
 
 		actual := GetString("MyKey", "MyDefaultVal")
 		if actual != expected {
@@ -213,7 +214,7 @@ func TestGetString(t *testing.T) {
 
 func TestGetNumber(t *testing.T) {
 	t.Run("Should return the default value if the local memory is empty", func(t *testing.T) {
-		localMemory = nil
+		Mock(nil)
 		defaultVal := 14.78
 		actual := GetNumber("MyKey", defaultVal)
 
@@ -225,9 +226,9 @@ func TestGetNumber(t *testing.T) {
 		}
 	})
 	t.Run("Should return the default value if the key value is empty", func(t *testing.T) {
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			"MyKey": "",
-		}
+		})
 		defaultVal := 14.78
 
 		actual := GetNumber("MyKey", defaultVal)
@@ -238,7 +239,7 @@ func TestGetNumber(t *testing.T) {
 			)
 		}
 
-		localMemory = map[string]string{}
+		Mock(map[string]string{})
 
 		actual = GetNumber("MyKey", defaultVal)
 		if actual != defaultVal {
@@ -249,10 +250,10 @@ func TestGetNumber(t *testing.T) {
 		}
 	})
 	t.Run("Should return the default value if the key type is empty", func(t *testing.T) {
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			"MyKey":      "1",
 			"MyKey.type": "",
-		}
+		})
 		defaultVal := 14.78
 
 		actual := GetNumber("MyKey", defaultVal)
@@ -263,9 +264,9 @@ func TestGetNumber(t *testing.T) {
 			)
 		}
 
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			"MyKey": "1",
-		}
+		})
 
 		actual = GetNumber("MyKey", defaultVal)
 		if actual != defaultVal {
@@ -276,10 +277,10 @@ func TestGetNumber(t *testing.T) {
 		}
 	})
 	t.Run("Should return the default value if the key value is a non number value", func(t *testing.T) {
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			"MyKey":      "not a number",
 			"MyKey.type": "number",
-		}
+		})
 
 		defaultVal := 14.78
 		actual := GetNumber("MyKey", defaultVal)
@@ -292,10 +293,10 @@ func TestGetNumber(t *testing.T) {
 		}
 	})
 	t.Run("Should return the default value if the key type is not 'number'", func(t *testing.T) {
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			"MyKey":      "100.5",
 			"MyKey.type": "not number",
-		}
+		})
 
 		defaultVal := 14.78
 		actual := GetNumber("MyKey", defaultVal)
@@ -308,10 +309,10 @@ func TestGetNumber(t *testing.T) {
 
 	})
 	t.Run("Should return the found value if the client returns a valid number", func(t *testing.T) {
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			"MyKey":      "2000.76",
 			"MyKey.type": "number",
-		}
+		})
 
 		expected := 2000.76
 		actual := GetNumber("MyKey", 100)
@@ -322,10 +323,10 @@ func TestGetNumber(t *testing.T) {
 			)
 		}
 
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			"MyKey":      "10",
 			"MyKey.type": "number",
-		}
+		})
 		expected = 10.0
 		actual = GetNumber("MyKey", 100)
 		if actual != expected {
@@ -339,7 +340,7 @@ func TestGetNumber(t *testing.T) {
 
 func TestIsEnabledByPercent(t *testing.T) {
 	t.Run("Should return false if the local memory is empty", func(t *testing.T) {
-		localMemory = nil
+		Mock(nil)
 
 		actual := IsEnabledByPercent("MyKey")
 		if actual {
@@ -350,9 +351,9 @@ func TestIsEnabledByPercent(t *testing.T) {
 		}
 	})
 	t.Run("Should return false if the key value is empty", func(t *testing.T) {
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			"MyKey": "",
-		}
+		})
 		actual := IsEnabledByPercent("MyKey")
 		if actual {
 			t.Errorf(
@@ -361,7 +362,7 @@ func TestIsEnabledByPercent(t *testing.T) {
 			)
 		}
 
-		localMemory = map[string]string{}
+		Mock(map[string]string{})
 		actual = IsEnabledByPercent("MyKey")
 		if actual {
 			t.Errorf(
@@ -371,10 +372,10 @@ func TestIsEnabledByPercent(t *testing.T) {
 		}
 	})
 	t.Run("Should return false if the key type is empty", func(t *testing.T) {
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			"MyKey":      "1",
 			"MyKey.type": "",
-		}
+		})
 		actual := IsEnabledByPercent("MyKey")
 		if actual {
 			t.Errorf(
@@ -383,9 +384,9 @@ func TestIsEnabledByPercent(t *testing.T) {
 			)
 		}
 
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			"MyKey": "1",
-		}
+		})
 		actual = IsEnabledByPercent("MyKey")
 		if actual {
 			t.Errorf(
@@ -395,10 +396,10 @@ func TestIsEnabledByPercent(t *testing.T) {
 		}
 	})
 	t.Run("Should return false if the key value is a non number value", func(t *testing.T) {
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			"MyKey":      "not a number",
 			"MyKey.type": "number",
-		}
+		})
 		actual := IsEnabledByPercent("MyKey")
 		if actual {
 			t.Errorf(
@@ -408,10 +409,10 @@ func TestIsEnabledByPercent(t *testing.T) {
 		}
 	})
 	t.Run("Should return false if the key type is not 'number'", func(t *testing.T) {
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			"MyKey":      "100.5",
 			"MyKey.type": "not number",
-		}
+		})
 		actual := IsEnabledByPercent("MyKey")
 		if actual {
 			t.Errorf(
@@ -421,10 +422,10 @@ func TestIsEnabledByPercent(t *testing.T) {
 		}
 	})
 	t.Run("Should return false if the key value is a non percentage value", func(t *testing.T) {
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			"MyKey":      "101",
 			"MyKey.type": "number",
-		}
+		})
 		actual := IsEnabledByPercent("MyKey")
 		if actual {
 			t.Errorf(
@@ -433,10 +434,10 @@ func TestIsEnabledByPercent(t *testing.T) {
 			)
 		}
 
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			"MyKey":      "-1",
 			"MyKey.type": "number",
-		}
+		})
 		actual = IsEnabledByPercent("MyKey")
 		if actual {
 			t.Errorf(
@@ -449,7 +450,7 @@ func TestIsEnabledByPercent(t *testing.T) {
 
 func TestGet(t *testing.T) {
 	t.Run("Should return the default value if the library was not initiated", func(t *testing.T) {
-		localMemory = nil
+		Mock(nil)
 
 		defaultVal := 10
 		result := Get("MyKey", defaultVal)
@@ -461,9 +462,9 @@ func TestGet(t *testing.T) {
 		}
 	})
 	t.Run("Should return the default value if the provided key has no value associated to it", func(t *testing.T) {
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			"anotherkey": "anotherval",
-		}
+		})
 
 		defaultVal := 10
 		result := Get("MyKey", defaultVal)
@@ -476,9 +477,9 @@ func TestGet(t *testing.T) {
 	})
 	t.Run("Should return the default value if the provided type (T) does not match with the value associated with the key", func(t *testing.T) {
 		key := "MyKey"
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			key: `"this is not a number"`,
-		}
+		})
 
 		defaultVal := 10
 		result := Get(key, defaultVal)
@@ -492,9 +493,9 @@ func TestGet(t *testing.T) {
 	t.Run("Should parse a string value correctly", func(t *testing.T) {
 		key := "MyKey"
 
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			key: "stringFeatureToggleValue",
-		}
+		})
 		result := Get(key, "")
 		if result != "stringFeatureToggleValue" {
 			t.Errorf("Failed to assert GetJSON result. Returned: %s", result)
@@ -503,9 +504,9 @@ func TestGet(t *testing.T) {
 	t.Run("Should parse a number value correctly", func(t *testing.T) {
 		key := "MyKey"
 
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			key: "10",
-		}
+		})
 		result := Get(key, 20)
 		if result != 10 {
 			t.Errorf("Failed to assert GetJSON result. Returned: %v", result)
@@ -514,9 +515,9 @@ func TestGet(t *testing.T) {
 	t.Run("Should parse a map value correctly", func(t *testing.T) {
 		key := "MyKey"
 
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			key: `{"mykey1": "myval", "mykey2": 20}`,
-		}
+		})
 
 		expected := map[string]any{
 			"mykey1": "myval",
@@ -534,9 +535,9 @@ func TestGet(t *testing.T) {
 			MyKey1 string `json:"mykey1"`
 			MyKey2 int    `json:"mykey2"`
 		}
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			key: `{"mykey1": "myval", "mykey2": 20}`,
-		}
+		})
 		expected := mockStruct{"myval", 20}
 		result := Get(key, mockStruct{})
 		if !reflect.DeepEqual(expected, result) {
@@ -545,9 +546,9 @@ func TestGet(t *testing.T) {
 	})
 	t.Run("Should parse a slice value correctly", func(t *testing.T) {
 		key := "MyKey"
-		localMemory = map[string]string{
+		Mock(map[string]string{
 			key: `["string", 42, 12]`,
-		}
+		})
 		expected := []any{"string", float64(42), float64(12)}
 		result := Get(key, []any{})
 		if !reflect.DeepEqual(result, expected) {
@@ -555,3 +556,131 @@ func TestGet(t *testing.T) {
 		}
 	})
 }
+
+func TestGetInt(t *testing.T) {
+	t.Run("Should return the default value if the key value is not an integer", func(t *testing.T) {
+		Mock(map[string]string{
+			"MyKey":      "10.5",
+			"MyKey.type": "integer",
+		})
+
+		actual := GetInt("MyKey", 3)
+		if actual != 3 {
+			t.Errorf("Should have returned the default value if the key value was not an integer, actualy returned %v", actual)
+		}
+	})
+	t.Run("Should return the found value if the key represents a valid integer", func(t *testing.T) {
+		for _, typ := range []string{"integer", "number"} {
+			Mock(map[string]string{
+				"MyKey":      "10",
+				"MyKey.type": typ,
+			})
+
+			actual := GetInt("MyKey", 3)
+			if actual != 10 {
+				t.Errorf("Should have returned the found value for type %s, actualy returned %v", typ, actual)
+			}
+		}
+	})
+	t.Run("Should accept integer values as numbers", func(t *testing.T) {
+		Mock(map[string]string{
+			"MyKey":      "10",
+			"MyKey.type": "integer",
+		})
+
+		actual := GetNumber("MyKey", 3)
+		if actual != 10 {
+			t.Errorf("Should have returned the found value, actualy returned %v", actual)
+		}
+	})
+}
+
+func TestGetDuration(t *testing.T) {
+	t.Run("Should return the default value if the key type is not 'duration'", func(t *testing.T) {
+		Mock(map[string]string{
+			"MyKey":      "1m",
+			"MyKey.type": "number",
+		})
+
+		actual := GetDuration("MyKey", time.Second)
+		if actual != time.Second {
+			t.Errorf("Should have returned the default value if the key type was not 'duration', actualy returned %v", actual)
+		}
+	})
+	t.Run("Should return the found value if the key represents a valid duration", func(t *testing.T) {
+		Mock(map[string]string{
+			"MyKey":      "1m30s",
+			"MyKey.type": "duration",
+		})
+
+		actual := GetDuration("MyKey", time.Second)
+		if actual != 90*time.Second {
+			t.Errorf("Should have returned the found value, actualy returned %v", actual)
+		}
+
+		result := Get("MyKey", time.Second)
+		if result != 90*time.Second {
+			t.Errorf("Should have parsed the duration with Get, actualy returned %v", result)
+		}
+	})
+}
+
+func TestGetTime(t *testing.T) {
+	t.Run("Should return the default value if the key value is not a RFC3339 datetime", func(t *testing.T) {
+		Mock(map[string]string{
+			"MyKey":      "2024-01-02",
+			"MyKey.type": "datetime",
+		})
+
+		defaultVal := time.Unix(0, 0)
+		actual := GetTime("MyKey", defaultVal)
+		if !actual.Equal(defaultVal) {
+			t.Errorf("Should have returned the default value if the key value was not a datetime, actualy returned %v", actual)
+		}
+	})
+	t.Run("Should return the found value if the key represents a valid datetime", func(t *testing.T) {
+		Mock(map[string]string{
+			"MyKey":      "2024-01-02T03:04:05Z",
+			"MyKey.type": "datetime",
+		})
+
+		expected := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		actual := GetTime("MyKey", time.Time{})
+		if !actual.Equal(expected) {
+			t.Errorf("Should have returned the found value, actualy returned %v", actual)
+		}
+
+		result := Get("MyKey", time.Time{})
+		if !result.Equal(expected) {
+			t.Errorf("Should have parsed the datetime with Get, actualy returned %v", result)
+		}
+	})
+}
+
+func TestGetStringSlice(t *testing.T) {
+	t.Run("Should return the default value if the key value is not a list of strings", func(t *testing.T) {
+		defaultVal := []string{"default"}
+		for _, val := range []string{"a,b", "[1, 2]"} {
+			Mock(map[string]string{
+				"MyKey":      val,
+				"MyKey.type": "list",
+			})
+
+			actual := GetStringSlice("MyKey", defaultVal)
+			if !reflect.DeepEqual(actual, defaultVal) {
+				t.Errorf("Should have returned the default value for %s, actualy returned %v", val, actual)
+			}
+		}
+	})
+	t.Run("Should return the found value if the key represents a valid list of strings", func(t *testing.T) {
+		Mock(map[string]string{
+			"MyKey":      `["a", "b"]`,
+			"MyKey.type": "list",
+		})
+
+		actual := GetStringSlice("MyKey", nil)
+		if !reflect.DeepEqual(actual, []string{"a", "b"}) {
+			t.Errorf("Should have returned the found value, actualy returned %v", actual)
+		}
+	})
+}
@@ -0,0 +1,198 @@
+package featuretoggle
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+var (
+	// all of the flags declared through the typed handle constructors, by key
+	registry   = map[string]flagDef{}
+	registryMu sync.RWMutex
+)
+
+// FlagInfo describes a flag declared through one of the typed handle constructors (Bool, Number, String or JSON).
+type FlagInfo struct {
+	// the feature toggle key
+	Key string
+	// the feature toggle type expected for the key ("boolean", "number", "string" or "json")
+	Type string
+	// the default value used when the feature toggle is not found or invalid
+	Default any
+}
+
+// flagDef represents a registered flag
+type flagDef struct {
+	info FlagInfo
+	// checks if a feature toggle value is valid for the flag, used by Validate
+	check func(e *entry) *ValidationError
+}
+
+// register adds a flag to the registry.
+// Panics if the key was already declared with a different type or default value,
+// so that each key has a single definition across the codebase.
+func register(info FlagInfo, check func(e *entry) *ValidationError) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	def, ok := registry[info.Key]
+	existing := def.info
+	if ok && (existing.Type != info.Type || !reflect.DeepEqual(existing.Default, info.Default)) {
+		panic(fmt.Sprintf(
+			"feature toggle %s redefined as %s with default %v, it was already defined as %s with default %v",
+			info.Key, info.Type, info.Default, existing.Type, existing.Default,
+		))
+	}
+
+	registry[info.Key] = flagDef{info, check}
+}
+
+// Flags returns all of the flags declared through the typed handle constructors, sorted by key.
+func Flags() []FlagInfo {
+	defs := flagDefs()
+
+	flags := make([]FlagInfo, len(defs))
+	for i, def := range defs {
+		flags[i] = def.info
+	}
+	return flags
+}
+
+// flagDefs returns all of the registered flags, sorted by key.
+func flagDefs() []flagDef {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	defs := make([]flagDef, 0, len(registry))
+	for _, def := range registry {
+		defs = append(defs, def)
+	}
+
+	sort.Slice(defs, func(i, j int) bool {
+		return defs[i].info.Key < defs[j].info.Key
+	})
+	return defs
+}
+
+// BoolFlag is a typed handle for a boolean feature toggle.
+type BoolFlag struct {
+	key        string
+	defaultVal bool
+}
+
+// Bool declares a boolean feature toggle, and registers it with its default value.
+//
+// Ex.:
+//
+//	var NewCheckout = featuretoggle.Bool("checkout.v2", false)
+func Bool(key string, defaultVal bool) *BoolFlag {
+	register(FlagInfo{Key: key, Type: "boolean", Default: defaultVal}, checkBoolean)
+	return &BoolFlag{key, defaultVal}
+}
+
+// Key returns the feature toggle key.
+func (f *BoolFlag) Key() string {
+	return f.key
+}
+
+// Enabled checks if the feature toggle is enabled, same as IsEnabled.
+func (f *BoolFlag) Enabled() bool {
+	return IsEnabled(f.key, f.defaultVal)
+}
+
+// EnabledCtx is the same as Enabled, but honours the overrides, the logger and the span carried by the context,
+// same as IsEnabledCtx.
+func (f *BoolFlag) EnabledCtx(ctx context.Context) bool {
+	return IsEnabledCtx(ctx, f.key, f.defaultVal)
+}
+
+// NumberFlag is a typed handle for a number feature toggle.
+type NumberFlag struct {
+	key        string
+	defaultVal float64
+}
+
+// Number declares a number feature toggle, and registers it with its default value.
+func Number(key string, defaultVal float64) *NumberFlag {
+	register(FlagInfo{Key: key, Type: "number", Default: defaultVal}, checkNumber)
+	return &NumberFlag{key, defaultVal}
+}
+
+// Key returns the feature toggle key.
+func (f *NumberFlag) Key() string {
+	return f.key
+}
+
+// Value returns the feature toggle value, same as GetNumber.
+func (f *NumberFlag) Value() float64 {
+	return GetNumber(f.key, f.defaultVal)
+}
+
+// ValueCtx is the same as Value, but honours the overrides, the logger and the span carried by the context,
+// same as GetNumberCtx.
+func (f *NumberFlag) ValueCtx(ctx context.Context) float64 {
+	return GetNumberCtx(ctx, f.key, f.defaultVal)
+}
+
+// StringFlag is a typed handle for a string feature toggle.
+type StringFlag struct {
+	key        string
+	defaultVal string
+}
+
+// String declares a string feature toggle, and registers it with its default value.
+func String(key string, defaultVal string) *StringFlag {
+	register(FlagInfo{Key: key, Type: "string", Default: defaultVal}, checkString)
+	return &StringFlag{key, defaultVal}
+}
+
+// Key returns the feature toggle key.
+func (f *StringFlag) Key() string {
+	return f.key
+}
+
+// Value returns the feature toggle value, same as GetString.
+func (f *StringFlag) Value() string {
+	return GetString(f.key, f.defaultVal)
+}
+
+// ValueCtx is the same as Value, but honours the overrides, the logger and the span carried by the context,
+// same as GetStringCtx.
+func (f *StringFlag) ValueCtx(ctx context.Context) string {
+	return GetStringCtx(ctx, f.key, f.defaultVal)
+}
+
+// JSONFlag is a typed handle for a feature toggle decoded into the type T.
+type JSONFlag[T any] struct {
+	key        string
+	defaultVal T
+}
+
+// JSON declares a feature toggle decoded into the type T, and registers it with its default value.
+//
+// Ex.:
+//
+//	var Limits = featuretoggle.JSON("checkout.limits", map[string]int{"daily": 5})
+func JSON[T any](key string, defaultVal T) *JSONFlag[T] {
+	register(FlagInfo{Key: key, Type: "json", Default: defaultVal}, checkDecode[T])
+	return &JSONFlag[T]{key, defaultVal}
+}
+
+// Key returns the feature toggle key.
+func (f *JSONFlag[T]) Key() string {
+	return f.key
+}
+
+// Value returns the feature toggle value, same as Get.
+func (f *JSONFlag[T]) Value() T {
+	return Get(f.key, f.defaultVal)
+}
+
+// ValueCtx is the same as Value, but honours the overrides, the logger and the span carried by the context,
+// same as GetCtx.
+func (f *JSONFlag[T]) ValueCtx(ctx context.Context) T {
+	return GetCtx(ctx, f.key, f.defaultVal)
+}
@@ -0,0 +1,75 @@
+package featuretoggle
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlags(t *testing.T) {
+	t.Run("Should return the values of the typed handles", func(t *testing.T) {
+		Mock(map[string]string{
+			"flag.bool":        "true",
+			"flag.bool.type":   "boolean",
+			"flag.number":      "7",
+			"flag.number.type": "number",
+			"flag.string":      "my val",
+			"flag.string.type": "string",
+			"flag.json":        `{"daily": 5}`,
+		})
+		defer Reset()
+
+		if !Bool("flag.bool", false).Enabled() {
+			t.Errorf("Should have returned the boolean value")
+		}
+		if v := Number("flag.number", 1).Value(); v != 7 {
+			t.Errorf("Should have returned the number value, returned %v", v)
+		}
+		if v := String("flag.string", "default").Value(); v != "my val" {
+			t.Errorf("Should have returned the string value, returned %v", v)
+		}
+		if v := JSON("flag.json", map[string]int{}).Value(); !reflect.DeepEqual(v, map[string]int{"daily": 5}) {
+			t.Errorf("Should have returned the json value, returned %v", v)
+		}
+	})
+	t.Run("Should return the default values of the typed handles", func(t *testing.T) {
+		Mock(map[string]string{})
+		defer Reset()
+
+		if !Bool("flag.default.bool", true).Enabled() {
+			t.Errorf("Should have returned the default boolean value")
+		}
+		if v := Number("flag.default.number", 3).Value(); v != 3 {
+			t.Errorf("Should have returned the default number value, returned %v", v)
+		}
+	})
+	t.Run("Should register the declared flags", func(t *testing.T) {
+		f := String("flag.registered", "default")
+
+		var found *FlagInfo
+		for _, info := range Flags() {
+			if info.Key == f.Key() {
+				info := info
+				found = &info
+			}
+		}
+
+		expected := &FlagInfo{Key: "flag.registered", Type: "string", Default: "default"}
+		if !reflect.DeepEqual(found, expected) {
+			t.Errorf("Should have registered the flag, registered %+v", found)
+		}
+	})
+	t.Run("Should allow declaring the same flag twice with the same default", func(t *testing.T) {
+		Bool("flag.twice", true)
+		Bool("flag.twice", true)
+	})
+	t.Run("Should panic if the same flag is declared with a different default", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Should have panicked if the flag was redefined")
+			}
+		}()
+
+		Bool("flag.redefined", true)
+		Bool("flag.redefined", false)
+	})
+}
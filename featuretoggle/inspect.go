@@ -0,0 +1,188 @@
+package featuretoggle
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Redacted replaces the values of the "secret" feature toggles when they are exposed (like in the span events)
+const Redacted = "[REDACTED]"
+
+// redacted returns the value held by the entry (its value, or one of its metadata fields with values),
+// or Redacted if the entry is of type "secret", so the secrets never reach the logs, events and error messages.
+func (e *entry) redacted(val string) string {
+	if e.typ == "secret" && val != "" {
+		return Redacted
+	}
+	return val
+}
+
+// maxChanges is how many changes are kept by RecentChanges
+const maxChanges = 100
+
+// Change represents a change of a field of the feature toggles hash, found when the feature toggles are reloaded
+type Change struct {
+	// the changed field (a feature toggle key, or a metadata field like "<key>.type")
+	Field string
+	// the previous value of the field, empty if the field was added
+	Old string
+	// the new value of the field, empty if the field was removed
+	New string
+	// when the change was found
+	Time time.Time
+}
+
+var (
+	// the most recent changes, oldest first
+	changes   []Change
+	changesMu sync.Mutex
+)
+
+// Snapshot returns a copy of the raw feature toggles in use (as found in redis), or nil if the library was not initiated.
+func Snapshot() map[string]string {
+	s := current()
+	if s == nil {
+		return nil
+	}
+
+	raw := make(map[string]string, len(s.raw))
+	for k, v := range s.raw {
+		raw[k] = v
+	}
+	return raw
+}
+
+// RecentChanges returns the most recent changes of the feature toggles of this instance, oldest first.
+// Only the last 100 changes are kept, and the changes are not shared between instances.
+// The values of the fields that were secret before or after the change (see Secret) are Redacted.
+func RecentChanges() []Change {
+	changesMu.Lock()
+	defer changesMu.Unlock()
+
+	return append([]Change(nil), changes...)
+}
+
+// recordChanges records the changes between the previous and the new raw feature toggles.
+// The secret values are redacted before being recorded, so the recorded changes never hold them.
+func recordChanges(prev map[string]string, raw map[string]string) {
+	now := time.Now()
+
+	var found []Change
+	for field, val := range raw {
+		if old, ok := prev[field]; !ok || old != val {
+			found = append(found, Change{Field: field, Old: old, New: val, Time: now})
+		}
+	}
+	for field, old := range prev {
+		if _, ok := raw[field]; !ok {
+			found = append(found, Change{Field: field, Old: old, Time: now})
+		}
+	}
+	for i, c := range found {
+		if Secret(prev, c.Field) || Secret(raw, c.Field) {
+			found[i].Old, found[i].New = redactValue(c.Old), redactValue(c.New)
+		}
+	}
+	if len(found) == 0 {
+		return
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].Field < found[j].Field
+	})
+
+	changesMu.Lock()
+	defer changesMu.Unlock()
+
+	changes = append(changes, found...)
+	if len(changes) > maxChanges {
+		changes = append([]Change(nil), changes[len(changes)-maxChanges:]...)
+	}
+}
+
+// redactValue returns Redacted, or an empty value if the value is empty.
+func redactValue(val string) string {
+	if val == "" {
+		return ""
+	}
+	return Redacted
+}
+
+// resetChanges discards the recorded changes.
+func resetChanges() {
+	changesMu.Lock()
+	defer changesMu.Unlock()
+
+	changes = nil
+}
+
+// Evaluation represents the result of the evaluation of a feature toggle of any type (see Evaluate)
+type Evaluation struct {
+	// the feature toggle key
+	Key string
+	// the declared type of the feature toggle, empty if it has no type
+	Type string
+	// the value parsed according to the declared type, nil if the feature toggle can not be used
+	Value any
+	// the raw value of the feature toggle
+	Raw string
+	// the reason of the result (like ReasonStatic, ReasonOverride or ReasonError)
+	Reason string
+	// the reason the feature toggle can not be used, if any (see KeyError)
+	Err error
+}
+
+// Evaluate evaluates the feature toggle of the key according to its declared type,
+// honouring the overrides carried by the context (see WithOverrides). Useful to inspect feature toggles of any type.
+//
+// The values are parsed the same way as the accessor of their types: bool for "boolean", float64 for "number",
+// int64 for "integer", time.Duration for "duration", time.Time for "datetime", the decoded json for "list" and "json",
+// and the raw string otherwise.
+func Evaluate(ctx context.Context, key string) Evaluation {
+	reason := ReasonStatic
+	if _, ok := overridden(ctx, key); ok {
+		reason = ReasonOverride
+	}
+
+	e, err := lookup(ctx, key, false)
+	return evaluate(key, e, err, reason)
+}
+
+// evaluate returns the evaluation of the entry of the key, or of the error found when looking it up.
+func evaluate(key string, e *entry, err error, reason string) Evaluation {
+	ev := Evaluation{Key: key, Reason: reason}
+	if err == nil && e.err != nil {
+		err = &KeyError{Key: key, Kind: ErrParse, Err: e.err}
+	}
+	if e != nil {
+		ev.Type, ev.Raw = e.typ, e.val
+	}
+	if err != nil {
+		ev.Reason, ev.Err = ReasonError, err
+		return ev
+	}
+
+	switch e.typ {
+	case "boolean":
+		ev.Value = e.boolean
+	case "number":
+		ev.Value = e.number
+	case "integer":
+		ev.Value = e.integer
+	case "duration":
+		ev.Value = e.duration
+	case "datetime":
+		ev.Value = e.time
+	case "list", "json":
+		err = decodeJSON(e.val, &ev.Value)
+		if err != nil {
+			ev.Reason, ev.Err = ReasonError, &KeyError{Key: key, Kind: ErrParse, Err: err}
+		}
+	default:
+		ev.Value = e.val
+	}
+
+	return ev
+}
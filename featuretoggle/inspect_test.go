@@ -0,0 +1,121 @@
+package featuretoggle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	toggles := map[string]string{"MyBool": "true", "MyBool.type": "boolean"}
+	Mock(toggles)
+	defer Reset()
+
+	snapshot := Snapshot()
+	if !reflect.DeepEqual(snapshot, toggles) {
+		t.Errorf("Should have returned the feature toggles in use, returned %v", snapshot)
+	}
+
+	snapshot["MyBool"] = "false"
+	if !IsEnabled("MyBool", false) {
+		t.Errorf("Should have returned a copy of the feature toggles")
+	}
+}
+
+func TestRecentChanges(t *testing.T) {
+	Mock(map[string]string{"MyBool": "true", "MyBool.type": "boolean", "MyString": "value"})
+	defer Reset()
+	resetChanges()
+
+	Mock(map[string]string{"MyBool": "false", "MyBool.type": "boolean", "MyNumber": "10"})
+
+	expected := []Change{
+		{Field: "MyBool", Old: "true", New: "false"},
+		{Field: "MyNumber", New: "10"},
+		{Field: "MyString", Old: "value"},
+	}
+	actual := RecentChanges()
+	for i := range actual {
+		actual[i].Time = time.Time{}
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Should have recorded the changes, recorded %+v", actual)
+	}
+
+	for i := 0; i < maxChanges; i++ {
+		Mock(map[string]string{"MyNumber": string(rune('a' + i%26))})
+	}
+	if len(RecentChanges()) != maxChanges {
+		t.Errorf("Should have kept only the last %d changes, kept %d", maxChanges, len(RecentChanges()))
+	}
+}
+
+func TestRecentChangesSecrets(t *testing.T) {
+	Mock(map[string]string{"MyToken": "s3cr3t", "MyToken.type": "secret", "MyToken.enum": `["s3cr3t"]`})
+	defer Reset()
+	resetChanges()
+
+	Mock(map[string]string{"MyToken": "value", "MyToken.type": "string"})
+	Mock(map[string]string{})
+
+	expected := []Change{
+		{Field: "MyToken", Old: Redacted, New: Redacted},
+		{Field: "MyToken.enum", Old: Redacted},
+		{Field: "MyToken.type", Old: "secret", New: "string"},
+		{Field: "MyToken", Old: "value"},
+		{Field: "MyToken.type", Old: "string"},
+	}
+	actual := RecentChanges()
+	for i := range actual {
+		actual[i].Time = time.Time{}
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Should have redacted the values of the fields that were secret, recorded %+v", actual)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	Mock(map[string]string{
+		"MyBool":         "true",
+		"MyBool.type":    "boolean",
+		"MyTimeout":      "1m30s",
+		"MyTimeout.type": "duration",
+		"MyJSON":         `{"daily": 5}`,
+		"MyJSON.type":    "json",
+		"MyNumber":       "ten",
+		"MyNumber.type":  "number",
+		"MyToken":        "s3cr3t",
+		"MyToken.type":   "secret",
+	})
+	defer Reset()
+
+	cases := map[string]any{
+		"MyBool":    true,
+		"MyTimeout": 90 * time.Second,
+		"MyJSON":    map[string]any{"daily": float64(5)},
+		"MyToken":   "s3cr3t",
+	}
+	for key, expected := range cases {
+		ev := Evaluate(context.Background(), key)
+		if ev.Err != nil || ev.Reason != ReasonStatic || !reflect.DeepEqual(ev.Value, expected) {
+			t.Errorf("Should have evaluated %s to %v, evaluated %+v", key, expected, ev)
+		}
+	}
+
+	ev := Evaluate(context.Background(), "MyNumber")
+	if !errors.Is(ev.Err, ErrParse) || ev.Reason != ReasonError || ev.Raw != "ten" || ev.Type != "number" {
+		t.Errorf("Should have returned the parse error, returned %+v", ev)
+	}
+
+	ctx := WithOverrides(context.Background(), map[string]string{"MyBool": "false"})
+	ev = Evaluate(ctx, "MyBool")
+	if ev.Value != false || ev.Reason != ReasonOverride {
+		t.Errorf("Should have evaluated the overridden value, evaluated %+v", ev)
+	}
+
+	if GetString("MyToken", "") != "s3cr3t" {
+		t.Errorf("Should have read the secret value as a string")
+	}
+}
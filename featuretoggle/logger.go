@@ -0,0 +1,130 @@
+package featuretoggle
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/delivery-much/dm-go/logger"
+)
+
+// DefaultLogInterval is the default interval in which each distinct miss reason of a key is logged at most once
+const DefaultLogInterval = time.Minute
+
+// Logger is the interface used by the library to write its logs.
+// The args are alternating key-value pairs, like in the log/slog package.
+//
+// A *slog.Logger implements Logger.
+type Logger interface {
+	InfoContext(ctx context.Context, msg string, args ...any)
+	ErrorContext(ctx context.Context, msg string, args ...any)
+}
+
+// SlogLogger returns a Logger that writes the logs to the slog logger l.
+// Uses slog.Default() if l is nil.
+func SlogLogger(l *slog.Logger) Logger {
+	if l == nil {
+		return slog.Default()
+	}
+	return l
+}
+
+// DMLogger returns a Logger that writes the logs to the dm-go logger.
+// It is the logger used by default.
+func DMLogger() Logger {
+	return dmLogger{}
+}
+
+// dmLogger adapts the dm-go logger to the Logger interface
+type dmLogger struct{}
+
+// InfoContext writes an info log to the dm-go logger
+func (dmLogger) InfoContext(_ context.Context, msg string, args ...any) {
+	logger.NoCTX().Infow(msg, args...)
+}
+
+// ErrorContext writes an error log to the dm-go logger
+func (dmLogger) ErrorContext(_ context.Context, msg string, args ...any) {
+	logger.NoCTX().Errorw(msg, args...)
+}
+
+// logState represents the logger in use, and how often the misses are logged
+type logState struct {
+	l        Logger
+	interval time.Duration
+}
+
+var (
+	// the logger in use
+	logs atomic.Pointer[logState]
+	// the last time each miss was logged, by key and reason (see logMiss)
+	missWindows sync.Map
+)
+
+func init() {
+	setLogger(nil, 0)
+}
+
+// setLogger sets the logger used by the library, and the interval in which each miss is logged.
+// Uses the dm-go logger if l is nil, and DefaultLogInterval if interval is zero.
+func setLogger(l Logger, interval time.Duration) {
+	if l == nil {
+		l = DMLogger()
+	}
+	if interval == 0 {
+		interval = DefaultLogInterval
+	}
+
+	logs.Store(&logState{l, interval})
+	missWindows.Range(func(k, _ any) bool {
+		missWindows.Delete(k)
+		return true
+	})
+}
+
+// logInfo writes an info log with the logger in use
+func logInfo(ctx context.Context, msg string, args ...any) {
+	logs.Load().l.InfoContext(ctx, msg, args...)
+}
+
+// logError writes an error log with the logger in use
+func logError(ctx context.Context, msg string, args ...any) {
+	logs.Load().l.ErrorContext(ctx, msg, args...)
+}
+
+// missWindow represents the rate limiting of the logs of a single miss reason of a key
+type missWindow struct {
+	// the last time the miss was logged, in unix nanoseconds
+	last atomic.Int64
+	// how many times the miss was not logged since the last log
+	suppressed atomic.Int64
+}
+
+// allowMiss checks if a miss of the key with the reason can be logged,
+// allowing each distinct miss at most once per log interval.
+//
+// returns true and how many misses were suppressed since the last log, if the miss can be logged.
+func allowMiss(key string, reason string, now time.Time) (bool, int64) {
+	interval := logs.Load().interval
+	if interval < 0 {
+		return true, 0
+	}
+
+	v, _ := missWindows.LoadOrStore(key+"\x00"+reason, &missWindow{})
+	w := v.(*missWindow)
+
+	last := w.last.Load()
+	if last != 0 && now.UnixNano()-last < int64(interval) {
+		w.suppressed.Add(1)
+		return false, 0
+	}
+	if !w.last.CompareAndSwap(last, now.UnixNano()) {
+		// another goroutine logged the miss concurrently
+		w.suppressed.Add(1)
+		return false, 0
+	}
+
+	return true, w.suppressed.Swap(0)
+}
@@ -0,0 +1,104 @@
+package featuretoggle
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordLogger is a Logger that records the log messages
+type recordLogger struct {
+	mu   sync.Mutex
+	msgs []string
+	args [][]any
+}
+
+func (r *recordLogger) InfoContext(_ context.Context, msg string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.msgs = append(r.msgs, msg)
+	r.args = append(r.args, args)
+}
+
+func (r *recordLogger) ErrorContext(ctx context.Context, msg string, args ...any) {
+	r.InfoContext(ctx, msg, args...)
+}
+
+func TestLogger(t *testing.T) {
+	t.Run("Should log each miss reason of a key once per interval", func(t *testing.T) {
+		Mock(map[string]string{"MyString": "value", "MyString.type": "string"})
+		defer Reset()
+		rec := &recordLogger{}
+		setLogger(rec, time.Hour)
+
+		for i := 0; i < 100; i++ {
+			IsEnabled("Missing", false)
+			GetNumber("MyString", 0)
+			GetString("MyString", "")
+		}
+
+		if len(rec.msgs) != 2 {
+			t.Fatalf("Should have logged each distinct miss once, logged %v", rec.msgs)
+		}
+		if !strings.HasPrefix(rec.msgs[0], "IsEnabled for key Missing") || !strings.HasPrefix(rec.msgs[1], "GetNumber for key MyString") {
+			t.Errorf("Should have logged the misses in order, logged %v", rec.msgs)
+		}
+	})
+	t.Run("Should log the suppressed misses count when the interval ends", func(t *testing.T) {
+		Mock(map[string]string{})
+		defer Reset()
+		setLogger(&recordLogger{}, time.Hour)
+
+		now := time.Now()
+		ok, _ := allowMiss("Missing", ErrNotFound.Error(), now)
+		if !ok {
+			t.Fatalf("Should have allowed the first miss")
+		}
+		for i := 0; i < 5; i++ {
+			ok, _ = allowMiss("Missing", ErrNotFound.Error(), now.Add(time.Minute))
+			if ok {
+				t.Fatalf("Should not have allowed the miss inside the interval")
+			}
+		}
+		ok, _ = allowMiss("Missing", ErrTypeMissing.Error(), now.Add(time.Minute))
+		if !ok {
+			t.Errorf("Should have allowed a different miss reason of the same key")
+		}
+
+		ok, suppressed := allowMiss("Missing", ErrNotFound.Error(), now.Add(time.Hour))
+		if !ok || suppressed != 5 {
+			t.Errorf("Should have allowed the miss after the interval with 5 suppressed misses, returned %v and %v", ok, suppressed)
+		}
+	})
+	t.Run("Should log every miss if the interval is negative", func(t *testing.T) {
+		Mock(map[string]string{})
+		defer Reset()
+		rec := &recordLogger{}
+		setLogger(rec, -1)
+
+		for i := 0; i < 10; i++ {
+			IsEnabled("Missing", false)
+		}
+
+		if len(rec.msgs) != 10 {
+			t.Errorf("Should have logged every miss, logged %v", len(rec.msgs))
+		}
+	})
+	t.Run("Should write the logs to the slog logger", func(t *testing.T) {
+		Mock(map[string]string{})
+		defer Reset()
+		var buf bytes.Buffer
+		setLogger(SlogLogger(slog.New(slog.NewTextHandler(&buf, nil))), 0)
+
+		GetString("Missing", "")
+
+		out := buf.String()
+		if !strings.Contains(out, "GetString for key Missing") || !strings.Contains(out, "method=GetString") {
+			t.Errorf("Should have written the miss to the slog logger, wrote %s", out)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package featuretoggle
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// the rebuilder of the cache started by Init, nil if the library was not initiated with Init
+var rebuilds atomic.Pointer[rebuilder]
+
+// rebuilder coalesces the requests to rebuild the cache, so that at most one rebuild is running at a time,
+// and every request made during the debounce window, or while a rebuild is running, is collapsed into a single rebuild.
+type rebuilder struct {
+	// the window in which the requests are collapsed, before the rebuild starts
+	debounce time.Duration
+	// rebuilds the cache
+	run func()
+
+	mu sync.Mutex
+	// signaled when no rebuild is scheduled or running
+	idle *sync.Cond
+	// whether a rebuild is scheduled to start after the debounce window
+	scheduled bool
+	// whether a rebuild is running
+	running bool
+	// whether a rebuild was requested while another one was running, so it must be scheduled once it finishes
+	pending bool
+	// whether the rebuilder was stopped, ignoring any further request
+	stopped bool
+	// the timer of the scheduled rebuild, if any
+	timer *time.Timer
+}
+
+// newRebuilder returns a rebuilder that collapses the requests made during the debounce window into a single call to run.
+func newRebuilder(debounce time.Duration, run func()) *rebuilder {
+	r := &rebuilder{debounce: debounce, run: run}
+	r.idle = sync.NewCond(&r.mu)
+	return r
+}
+
+// requestRebuild requests the cache to be rebuilt, through the rebuilder started by Init, if any.
+func requestRebuild() {
+	r := rebuilds.Load()
+	if r == nil {
+		rebuild()
+		return
+	}
+	r.request()
+}
+
+// request requests a rebuild. Does nothing if a rebuild is already scheduled,
+// and schedules another rebuild after the running one finishes, so that the changes made while it was running are loaded.
+func (r *rebuilder) request() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case r.stopped, r.scheduled, r.pending:
+		return
+	case r.running:
+		r.pending = true
+		return
+	}
+	r.schedule()
+}
+
+// schedule starts a rebuild after the debounce window. Must be called with the lock held.
+func (r *rebuilder) schedule() {
+	r.scheduled = true
+	if r.debounce <= 0 {
+		go r.start()
+		return
+	}
+	r.timer = time.AfterFunc(r.debounce, r.start)
+}
+
+// start runs the scheduled rebuild, and schedules the pending one once it finishes, if any.
+func (r *rebuilder) start() {
+	r.mu.Lock()
+	r.scheduled = false
+	if r.stopped {
+		r.idle.Broadcast()
+		r.mu.Unlock()
+		return
+	}
+	r.running = true
+	r.mu.Unlock()
+
+	r.run()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.running = false
+	if r.pending && !r.stopped {
+		r.pending = false
+		r.schedule()
+		return
+	}
+	r.pending = false
+	r.idle.Broadcast()
+}
+
+// stop stops the rebuilder, cancelling the scheduled rebuild, and waits for the running one to finish.
+func (r *rebuilder) stop() {
+	r.mu.Lock()
+	r.stopped = true
+	if r.timer != nil && r.timer.Stop() {
+		r.scheduled = false
+	}
+	r.mu.Unlock()
+
+	r.wait()
+}
+
+// wait waits until no rebuild is scheduled or running.
+func (r *rebuilder) wait() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for r.scheduled || r.running {
+		r.idle.Wait()
+	}
+}
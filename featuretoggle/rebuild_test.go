@@ -0,0 +1,103 @@
+package featuretoggle
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// hsetMessage is the keyspace notification published by redis when the service hash is written
+func hsetMessage() *redis.Message {
+	return &redis.Message{Channel: "__keyspace@0__:MyService", Payload: "hset"}
+}
+
+func TestRebuilder(t *testing.T) {
+	t.Run("Should collapse the notifications received during the debounce window into a single rebuild", func(t *testing.T) {
+		defer Reset()
+		fake := &fakeRedis{toggles: map[string]string{"MyBool": "true", "MyBool.type": "boolean"}}
+		client, serviceName = fake, "MyService"
+		r := newRebuilder(50*time.Millisecond, rebuild)
+		rebuilds.Store(r)
+
+		for i := 0; i < 200; i++ {
+			handleUpdate(hsetMessage())
+		}
+		r.wait()
+
+		if fake.calls != 1 {
+			t.Errorf("Should have called HGETALL once for 200 notifications, called %d times", fake.calls)
+		}
+		if !IsEnabled("MyBool", false) {
+			t.Errorf("Should have rebuilt the cache")
+		}
+	})
+	t.Run("Should run a single rebuild at a time, collapsing the notifications received while running", func(t *testing.T) {
+		defer Reset()
+		fake := &fakeRedis{
+			toggles: map[string]string{"MyBool": "true", "MyBool.type": "boolean"},
+			entered: make(chan struct{}),
+			release: make(chan struct{}),
+		}
+		client, serviceName = fake, "MyService"
+
+		var running, maxRunning atomic.Int32
+		r := newRebuilder(0, func() {
+			n := running.Add(1)
+			if n > maxRunning.Load() {
+				maxRunning.Store(n)
+			}
+			rebuild()
+			running.Add(-1)
+		})
+		rebuilds.Store(r)
+
+		handleUpdate(hsetMessage())
+		<-fake.entered
+
+		for i := 0; i < 199; i++ {
+			handleUpdate(hsetMessage())
+		}
+		fake.release <- struct{}{}
+
+		// the notifications received while running are loaded by a single follow-up rebuild
+		<-fake.entered
+		fake.release <- struct{}{}
+		r.wait()
+
+		if fake.calls != 2 {
+			t.Errorf("Should have called HGETALL twice for 200 notifications, called %d times", fake.calls)
+		}
+		if maxRunning.Load() != 1 {
+			t.Errorf("Should have run a single rebuild at a time, ran %d", maxRunning.Load())
+		}
+	})
+	t.Run("Should ignore the notifications of other services", func(t *testing.T) {
+		defer Reset()
+		fake := &fakeRedis{toggles: map[string]string{}}
+		client, serviceName = fake, "MyService"
+		r := newRebuilder(0, rebuild)
+		rebuilds.Store(r)
+
+		handleUpdate(&redis.Message{Channel: "__keyspace@0__:OtherService", Payload: "hset"})
+		r.wait()
+
+		if fake.calls != 0 {
+			t.Errorf("Should not have rebuilt the cache, called HGETALL %d times", fake.calls)
+		}
+	})
+	t.Run("Should cancel the scheduled rebuild when stopped", func(t *testing.T) {
+		calls := 0
+		r := newRebuilder(time.Hour, func() { calls++ })
+
+		r.request()
+		r.stop()
+		r.request()
+		r.wait()
+
+		if calls != 0 {
+			t.Errorf("Should not have rebuilt the cache after stopped, rebuilt %d times", calls)
+		}
+	})
+}
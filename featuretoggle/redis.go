@@ -1,15 +1,20 @@
 package featuretoggle
 
 import (
+	"context"
 	"fmt"
+	"time"
 
-	"github.com/delivery-much/dm-go/logger"
 	"github.com/go-redis/redis"
 )
 
 type redisClient interface {
 	subscribe(pattern string) (subs *redis.PubSub)
 	hgetall(namespace string) (map[string]string, error)
+	hget(namespace string, field string) (string, error)
+	xread(stream string, id string, count int64, block time.Duration) ([]redis.XMessage, error)
+	lastID(stream string) (string, error)
+	ping() error
 }
 
 // RedisDB represents the Redis database client
@@ -27,9 +32,9 @@ func getRedisClient(host string, port string, db int) (rc redisClient, err error
 
 	configRes := client.ConfigSet("notify-keyspace-events", "KEA")
 	if configRes == nil || configRes.Err() != nil {
-		logger.NoCTX().Errorf(
-			"Failed to configure feature toggle redis client to notify changes: %s. The library will be initiated anyway",
-			configRes.Err().Error(),
+		logError(context.Background(),
+			"Failed to configure feature toggle redis client to notify changes, the library will be initiated anyway",
+			"error", configRes.Err().Error(),
 		)
 	}
 
@@ -58,3 +63,45 @@ func (db *redisDB) hgetall(namespace string) (m map[string]string, err error) {
 	m = resp.Val()
 	return
 }
+
+// hget gets a single field of the given namespace, empty if the field was not found
+func (db *redisDB) hget(namespace string, field string) (string, error) {
+	val, err := db.Client.HGet(namespace, field).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return val, err
+}
+
+// xread reads the entries of the stream after the id, waiting up to block for them.
+// returns no entries if there were none
+func (db *redisDB) xread(stream string, id string, count int64, block time.Duration) ([]redis.XMessage, error) {
+	streams, err := db.Client.XRead(&redis.XReadArgs{Streams: []string{stream, id}, Count: count, Block: block}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(streams) == 0 {
+		return nil, nil
+	}
+	return streams[0].Messages, nil
+}
+
+// lastID returns the id of the last entry of the stream, "0" if the stream is empty
+func (db *redisDB) lastID(stream string) (string, error) {
+	msgs, err := db.Client.XRevRangeN(stream, "+", "-", 1).Result()
+	if err != nil {
+		return "", err
+	}
+	if len(msgs) == 0 {
+		return "0", nil
+	}
+	return msgs[0].ID, nil
+}
+
+// ping checks the connection with redis
+func (db *redisDB) ping() error {
+	return db.Client.Ping().Err()
+}
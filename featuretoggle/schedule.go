@@ -0,0 +1,188 @@
+package featuretoggle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// scheduleLayout is the layout of the times of the schedule transitions without offset, read in the time zone of the schedule
+const scheduleLayout = "2006-01-02T15:04:05"
+
+// scheduleField represents the "<key>.schedule" metadata field, like:
+//
+//	{"timezone": "America/Sao_Paulo", "transitions": [{"at": "2024-11-29T00:00:00", "value": "true"}]}
+type scheduleField struct {
+	// the IANA time zone of the times without offset, UTC if empty
+	Timezone    string `json:"timezone"`
+	Transitions []struct {
+		// when the value starts to be used, in RFC 3339 or in the time zone of the schedule (like "2024-11-29T00:00:00")
+		At string `json:"at"`
+		// the value, as saved in redis (json numbers and booleans are also accepted)
+		Value json.RawMessage `json:"value"`
+	} `json:"transitions"`
+}
+
+// transition represents a scheduled value of a feature toggle, used from its time until the next transition
+type transition struct {
+	at time.Time
+	e  *entry
+}
+
+// compileSchedule parses the "<key>.schedule" metadata field into the schedule of the entry,
+// compiling the value of each transition the same way as the key value.
+//
+// The invalid scheduled values are kept in the schedule with their violations, so that they are rejected only when they start,
+// and are also reported in the entry scheduleViolations, so that a typo is found before it is used.
+// returns a violation if the schedule itself is invalid, since its transitions are unknown.
+func compileSchedule(raw map[string]string, key string, e *entry) *Violation {
+	rawSchedule, ok := metadata(raw, key, "schedule")
+	if !ok {
+		return nil
+	}
+
+	violation := func(err error) *Violation {
+		return &Violation{Key: key, Constraint: "schedule", Value: e.redacted(e.val), Err: err}
+	}
+
+	var field scheduleField
+	err := json.Unmarshal([]byte(rawSchedule), &field)
+	if err != nil {
+		return violation(fmt.Errorf("invalid schedule %s: %w", e.redacted(rawSchedule), err))
+	}
+	loc, err := time.LoadLocation(field.Timezone)
+	if err != nil {
+		return violation(fmt.Errorf("invalid schedule time zone: %w", err))
+	}
+
+	schedule := make([]transition, 0, len(field.Transitions))
+	for _, t := range field.Transitions {
+		at, err := time.Parse(time.RFC3339, t.At)
+		if err != nil {
+			at, err = time.ParseInLocation(scheduleLayout, t.At, loc)
+		}
+		if err != nil {
+			return violation(fmt.Errorf("invalid schedule time %q, expected RFC 3339 or %s", t.At, scheduleLayout))
+		}
+
+		val := string(t.Value)
+		var s string
+		if json.Unmarshal(t.Value, &s) == nil {
+			val = s
+		}
+
+		te := compileValue(raw, key, val)
+		switch {
+		case te.err != nil:
+			te.violation = &Violation{Key: key, Constraint: "schedule", Value: te.redacted(val), Err: fmt.Errorf("the value scheduled at %s is invalid: %w", t.At, te.err)}
+		case te.violation != nil:
+			te.violation = &Violation{Key: key, Constraint: "schedule", Value: te.redacted(val), Err: fmt.Errorf("the value scheduled at %s is invalid: %w", t.At, te.violation)}
+		}
+		if te.violation != nil {
+			e.scheduleViolations = append(e.scheduleViolations, te.violation)
+		}
+		schedule = append(schedule, transition{at: at, e: te})
+	}
+
+	sort.SliceStable(schedule, func(i, j int) bool {
+		return schedule[i].at.Before(schedule[j].at)
+	})
+	e.schedule = schedule
+	return nil
+}
+
+// at returns the entry in use at the time: the value of the last transition of the schedule started until then,
+// or the entry itself if no transition started yet.
+func (e *entry) at(now time.Time) *entry {
+	res := e
+	for _, t := range e.schedule {
+		if t.at.After(now) {
+			break
+		}
+		res = t.e
+	}
+	return res
+}
+
+// nextTransition returns the time of the next transition of the snapshot after now, and the keys that change at that time.
+// returns false if no transition is scheduled.
+func (s *snapshot) nextTransition(now time.Time) (time.Time, []string, bool) {
+	if s == nil {
+		return time.Time{}, nil, false
+	}
+
+	var next time.Time
+	var keys []string
+	for key, e := range s.entries {
+		for _, t := range e.schedule {
+			if !t.at.After(now) {
+				continue
+			}
+
+			switch {
+			case next.IsZero() || t.at.Before(next):
+				next, keys = t.at, []string{key}
+			case t.at.Equal(next):
+				keys = append(keys, key)
+			}
+			break
+		}
+	}
+
+	sort.Strings(keys)
+	return next, keys, !next.IsZero()
+}
+
+var (
+	// the timer that refreshes the bindings at the next transition of the snapshot in use
+	scheduleTimer *time.Timer
+	// incremented whenever the timer is replaced by the one of another snapshot, so that a stale timer does nothing
+	scheduleGen     uint64
+	scheduleTimerMu sync.Mutex
+)
+
+// watchSchedules refreshes the bindings (see Bind) when the next scheduled value of the snapshot starts,
+// replacing the timer of the previous snapshot.
+func watchSchedules(s *snapshot) {
+	scheduleTimerMu.Lock()
+	defer scheduleTimerMu.Unlock()
+
+	if scheduleTimer != nil {
+		scheduleTimer.Stop()
+		scheduleTimer = nil
+	}
+	scheduleGen++
+	armSchedule(s, scheduleGen)
+}
+
+// armSchedule starts the timer of the next transition of the snapshot. Must be called with the lock held.
+func armSchedule(s *snapshot, gen uint64) {
+	next, keys, ok := s.nextTransition(time.Now())
+	if !ok {
+		return
+	}
+
+	scheduleTimer = time.AfterFunc(time.Until(next), func() {
+		transitioned(s, gen, keys)
+	})
+}
+
+// transitioned refreshes the bindings when the transitions of the keys started,
+// and starts the timer of the next transition, unless the timer was replaced by the one of another snapshot.
+func transitioned(s *snapshot, gen uint64, keys []string) {
+	scheduleTimerMu.Lock()
+	if gen != scheduleGen {
+		scheduleTimerMu.Unlock()
+		return
+	}
+	armSchedule(s, gen)
+	scheduleTimerMu.Unlock()
+
+	for _, key := range keys {
+		logInfo(context.Background(), "[Feature Toggle] A scheduled value started to be used", "key", key)
+	}
+	refreshBindings()
+}
@@ -0,0 +1,172 @@
+package featuretoggle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+// scheduleOf returns a "<key>.schedule" field with the transitions, as pairs of times and values
+func scheduleOf(timezone string, transitions ...string) string {
+	s := fmt.Sprintf(`{"timezone": %q, "transitions": [`, timezone)
+	for i := 0; i < len(transitions); i += 2 {
+		if i > 0 {
+			s += ", "
+		}
+		s += fmt.Sprintf(`{"at": %q, "value": %s}`, transitions[i], transitions[i+1])
+	}
+	return s + "]}"
+}
+
+func TestSchedule(t *testing.T) {
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+	t.Run("Should use the value of the last transition started", func(t *testing.T) {
+		Mock(map[string]string{
+			"Launch":          "false",
+			"Launch.type":     "boolean",
+			"Launch.schedule": scheduleOf("", future, `"false"`, past, `"true"`),
+			"Limit":           "10",
+			"Limit.type":      "integer",
+			"Limit.schedule":  scheduleOf("", future, "20"),
+		})
+		defer Reset()
+
+		if !IsEnabled("Launch", false) {
+			t.Errorf("Should have used the value scheduled in the past")
+		}
+		if GetInt("Limit", 0) != 10 {
+			t.Errorf("Should have used the value of the key before the first transition, returned %d", GetInt("Limit", 0))
+		}
+		if ev := Evaluate(context.Background(), "Launch"); ev.Value != true || ev.Raw != "true" {
+			t.Errorf("Should have evaluated the scheduled value, evaluated %+v", ev)
+		}
+	})
+	t.Run("Should read the times without offset in the time zone of the schedule", func(t *testing.T) {
+		raw := map[string]string{
+			"Launch":          "false",
+			"Launch.type":     "boolean",
+			"Launch.schedule": scheduleOf("America/Sao_Paulo", "2024-11-29T00:00:00", "true", "2024-12-02T00:00:00Z", "false"),
+		}
+
+		e, _ := compileEntry(raw, "Launch")
+		if e.violation != nil || len(e.schedule) != 2 {
+			t.Fatalf("Should have compiled the schedule, compiled %+v", e)
+		}
+		if at := e.schedule[0].at.UTC(); !at.Equal(time.Date(2024, 11, 29, 3, 0, 0, 0, time.UTC)) {
+			t.Errorf("Should have read the time in the time zone, read %v", at)
+		}
+
+		if e.at(time.Date(2024, 11, 29, 2, 59, 59, 0, time.UTC)).boolean {
+			t.Errorf("Should have used the value of the key before the transition")
+		}
+		if !e.at(time.Date(2024, 11, 29, 3, 0, 0, 0, time.UTC)).boolean {
+			t.Errorf("Should have used the scheduled value exactly at the transition")
+		}
+		if e.at(time.Date(2024, 12, 2, 0, 0, 0, 0, time.UTC)).boolean {
+			t.Errorf("Should have used the value of the last transition")
+		}
+	})
+	t.Run("Should reject the key if the schedule is invalid", func(t *testing.T) {
+		cases := map[string]string{
+			"invalid json":      "{",
+			"invalid time zone": scheduleOf("Mars/Olympus", past, "true"),
+			"invalid time":      scheduleOf("", "tomorrow", "true"),
+			"invalid value":     scheduleOf("", past, `"maybe"`),
+			"violation":         `{"transitions": [{"at": "2024-11-29T00:00:00", "value": "true"}]}`,
+		}
+		for name, schedule := range cases {
+			raw := map[string]string{"Launch": "false", "Launch.type": "boolean", "Launch.schedule": schedule}
+			if name == "violation" {
+				raw["Launch.enum"] = `["false"]`
+			}
+
+			err := Check(raw, "Launch")
+			if !errors.Is(err, ErrConstraintViolation) {
+				t.Errorf("Should have rejected the %s, returned %v", name, err)
+			}
+		}
+	})
+	t.Run("Should keep using the current value until an invalid scheduled value starts", func(t *testing.T) {
+		Mock(map[string]string{
+			"Launch":          "true",
+			"Launch.type":     "boolean",
+			"Launch.schedule": scheduleOf("", future, `"maybe"`),
+			"Limit":           "5",
+			"Limit.type":      "integer",
+			"Limit.max":       "10",
+			"Limit.schedule":  scheduleOf("", past, "20"),
+		})
+		defer Reset()
+
+		if !IsEnabled("Launch", false) {
+			t.Errorf("Should have used the current value before the invalid scheduled value starts")
+		}
+		if _, err := GetIntE("Limit"); !errors.Is(err, ErrConstraintViolation) {
+			t.Errorf("Should have rejected the invalid scheduled value once it started, returned %v", err)
+		}
+
+		violations := Violations()
+		if len(violations) != 2 || violations[0].Key != "Launch" || violations[0].Constraint != "schedule" || violations[0].Value != "maybe" {
+			t.Errorf("Should have reported the invalid scheduled values, reported %+v", violations)
+		}
+	})
+	t.Run("Should refresh the bindings when a scheduled value starts", func(t *testing.T) {
+		soon := time.Now().Add(100 * time.Millisecond).UTC().Format(time.RFC3339Nano)
+		Mock(map[string]string{
+			"checkout.enabled":          "false",
+			"checkout.enabled.type":     "boolean",
+			"checkout.enabled.schedule": scheduleOf("", soon, "true"),
+		})
+		defer Reset()
+
+		var cfg bindConfig
+		b, _, err := Bind(&cfg)
+		if err != nil {
+			t.Fatalf("Should have bound the struct, returned %v", err)
+		}
+		if b.Load().Enabled {
+			t.Fatalf("Should not have used the scheduled value before its time")
+		}
+
+		deadline := time.Now().Add(2 * time.Second)
+		for !b.Load().Enabled && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if !b.Load().Enabled {
+			t.Errorf("Should have refreshed the binding with the scheduled value")
+		}
+	})
+}
+
+func TestWatchSchedules(t *testing.T) {
+	t.Run("Should not replace the timer of a newer snapshot when a stale timer fires", func(t *testing.T) {
+		defer Reset()
+		future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+		old := compile(map[string]string{"Launch": "false", "Launch.type": "boolean", "Launch.schedule": scheduleOf("", future, `"true"`)}, nil)
+		s := compile(map[string]string{"Other": "false", "Other.type": "boolean", "Other.schedule": scheduleOf("", future, `"true"`)}, nil)
+
+		watchSchedules(old)
+		scheduleTimerMu.Lock()
+		staleGen := scheduleGen
+		scheduleTimerMu.Unlock()
+
+		watchSchedules(s)
+		scheduleTimerMu.Lock()
+		timer, gen := scheduleTimer, scheduleGen
+		scheduleTimerMu.Unlock()
+
+		// the timer of the old snapshot fires after the new snapshot was stored
+		transitioned(old, staleGen, []string{"Launch"})
+
+		scheduleTimerMu.Lock()
+		defer scheduleTimerMu.Unlock()
+		if scheduleTimer != timer || scheduleGen != gen {
+			t.Errorf("Should have kept the timer of the newer snapshot")
+		}
+	})
+}
@@ -0,0 +1,499 @@
+package featuretoggle
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+schema represents a compiled json schema, used to validate json feature toggle values before they are decoded.
+
+Only a subset of the JSON Schema draft 2020-12 is supported:
+
+- type (a single type or a list of types), enum and const;
+
+- properties, required and additionalProperties (a boolean or a schema);
+
+- items, minItems and maxItems;
+
+- minLength, maxLength and pattern;
+
+- minimum, maximum, exclusiveMinimum and exclusiveMaximum;
+
+- allOf, anyOf, oneOf and not;
+
+- the boolean schemas true and false.
+
+Annotations (title, description, $schema, etc.) are ignored.
+References ($ref, $dynamicRef) and the other keywords (minProperties, uniqueItems, format, etc.) are not supported,
+and fail the schema compilation, so a value is never accepted by a keyword that was not checked.
+*/
+type schema struct {
+	// set for the boolean schemas true and false
+	always *bool
+
+	types []string
+	enum  []any
+	konst *any
+
+	properties           map[string]*schema
+	required             []string
+	additionalProperties *schema
+
+	items    *schema
+	minItems *int
+	maxItems *int
+
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+
+	minimum          *float64
+	maximum          *float64
+	exclusiveMinimum *float64
+	exclusiveMaximum *float64
+
+	allOf []*schema
+	anyOf []*schema
+	oneOf []*schema
+	not   *schema
+}
+
+// checkSchema validates the entry value against the json schema declared in its "<key>.schema" metadata field, if any.
+// Only values that were parsed successfully are checked, and an invalid schema rejects the value.
+func checkSchema(key string, e *entry, raw map[string]string) *Violation {
+	rawSchema, ok := metadata(raw, key, "schema")
+	if !ok || e.blank || e.err != nil {
+		return nil
+	}
+
+	violation := func(err error) *Violation {
+		return &Violation{Key: key, Constraint: "schema", Value: e.redacted(e.val), Err: err}
+	}
+
+	sch, err := compileSchema(rawSchema)
+	if err != nil {
+		return violation(fmt.Errorf("invalid schema: %w", err))
+	}
+
+	var payload any
+	err = decodeJSON(e.val, &payload)
+	if err != nil {
+		return violation(fmt.Errorf("invalid json value: %w", err))
+	}
+
+	err = sch.validate(payload, "#")
+	if err != nil && e.typ == "secret" {
+		// the validation errors may show parts of the value
+		err = fmt.Errorf("the value does not match the schema")
+	}
+	if err != nil {
+		return violation(err)
+	}
+	return nil
+}
+
+// compileSchema parses and compiles a json schema.
+func compileSchema(raw string) (*schema, error) {
+	var doc any
+	err := decodeJSON(raw, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema json: %w", err)
+	}
+
+	return compileSchemaNode(doc, "#")
+}
+
+// compileSchemaNode compiles a json schema node, path is used to locate errors.
+func compileSchemaNode(node any, path string) (*schema, error) {
+	if b, ok := node.(bool); ok {
+		return &schema{always: &b}, nil
+	}
+
+	obj, ok := node.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: a schema must be an object or a boolean", path)
+	}
+
+	s := &schema{}
+	for kw, val := range obj {
+		var err error
+		kwPath := path + "/" + kw
+
+		switch kw {
+		case "$ref", "$dynamicRef":
+			err = fmt.Errorf("%s: references are not supported", kwPath)
+		case "type":
+			s.types, err = schemaTypes(val, kwPath)
+		case "enum":
+			list, ok := val.([]any)
+			if !ok {
+				err = fmt.Errorf("%s: must be an array", kwPath)
+			}
+			s.enum = list
+		case "const":
+			v := val
+			s.konst = &v
+		case "properties":
+			props, ok := val.(map[string]any)
+			if !ok {
+				err = fmt.Errorf("%s: must be an object", kwPath)
+				break
+			}
+			s.properties = map[string]*schema{}
+			for name, p := range props {
+				s.properties[name], err = compileSchemaNode(p, kwPath+"/"+name)
+				if err != nil {
+					break
+				}
+			}
+		case "required":
+			s.required, err = schemaStrings(val, kwPath)
+		case "additionalProperties":
+			s.additionalProperties, err = compileSchemaNode(val, kwPath)
+		case "items":
+			s.items, err = compileSchemaNode(val, kwPath)
+		case "minItems":
+			s.minItems, err = schemaInt(val, kwPath)
+		case "maxItems":
+			s.maxItems, err = schemaInt(val, kwPath)
+		case "minLength":
+			s.minLength, err = schemaInt(val, kwPath)
+		case "maxLength":
+			s.maxLength, err = schemaInt(val, kwPath)
+		case "pattern":
+			p, ok := val.(string)
+			if !ok {
+				err = fmt.Errorf("%s: must be a string", kwPath)
+				break
+			}
+			s.pattern, err = regexp.Compile(p)
+		case "minimum":
+			s.minimum, err = schemaNumber(val, kwPath)
+		case "maximum":
+			s.maximum, err = schemaNumber(val, kwPath)
+		case "exclusiveMinimum":
+			s.exclusiveMinimum, err = schemaNumber(val, kwPath)
+		case "exclusiveMaximum":
+			s.exclusiveMaximum, err = schemaNumber(val, kwPath)
+		case "allOf":
+			s.allOf, err = schemaList(val, kwPath)
+		case "anyOf":
+			s.anyOf, err = schemaList(val, kwPath)
+		case "oneOf":
+			s.oneOf, err = schemaList(val, kwPath)
+		case "not":
+			s.not, err = compileSchemaNode(val, kwPath)
+		case "$schema", "$id", "$comment", "$defs", "title", "description", "examples", "default", "deprecated", "readOnly", "writeOnly":
+			// annotations, that do not change the validation
+		default:
+			err = fmt.Errorf("%s: the keyword is not supported", kwPath)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// validate checks if the json value v satisfies the schema, path is used to locate errors.
+func (s *schema) validate(v any, path string) error {
+	if s.always != nil {
+		if !*s.always {
+			return fmt.Errorf("%s: no value is allowed", path)
+		}
+		return nil
+	}
+
+	if len(s.types) > 0 && !matchesType(v, s.types) {
+		return fmt.Errorf("%s: expected %s, found %s", path, strings.Join(s.types, " or "), jsonType(v))
+	}
+
+	if s.konst != nil && !reflect.DeepEqual(v, *s.konst) {
+		return fmt.Errorf("%s: expected the constant value %v", path, *s.konst)
+	}
+
+	if s.enum != nil {
+		found := false
+		for _, allowed := range s.enum {
+			if reflect.DeepEqual(v, allowed) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: the value %v is not one of %v", path, v, s.enum)
+		}
+	}
+
+	var err error
+	switch val := v.(type) {
+	case map[string]any:
+		err = s.validateObject(val, path)
+	case []any:
+		err = s.validateArray(val, path)
+	case string:
+		err = s.validateString(val, path)
+	case float64:
+		err = s.validateNumber(val, path)
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.validateComposition(v, path)
+}
+
+// validateObject checks the object keywords of the schema
+func (s *schema) validateObject(obj map[string]any, path string) error {
+	for _, name := range s.required {
+		if _, ok := obj[name]; !ok {
+			return fmt.Errorf("%s: missing required property %s", path, name)
+		}
+	}
+
+	// validate in a stable order, so the reported error is always the same
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		propPath := path + "/" + name
+		if p, ok := s.properties[name]; ok {
+			err := p.validate(obj[name], propPath)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if s.additionalProperties != nil {
+			err := s.additionalProperties.validate(obj[name], propPath)
+			if err != nil {
+				return fmt.Errorf("%s: additional property not allowed: %w", propPath, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateArray checks the array keywords of the schema
+func (s *schema) validateArray(list []any, path string) error {
+	if s.minItems != nil && len(list) < *s.minItems {
+		return fmt.Errorf("%s: expected at least %d items, found %d", path, *s.minItems, len(list))
+	}
+	if s.maxItems != nil && len(list) > *s.maxItems {
+		return fmt.Errorf("%s: expected at most %d items, found %d", path, *s.maxItems, len(list))
+	}
+
+	if s.items != nil {
+		for i, item := range list {
+			err := s.items.validate(item, fmt.Sprintf("%s/%d", path, i))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateString checks the string keywords of the schema
+func (s *schema) validateString(str string, path string) error {
+	length := utf8.RuneCountInString(str)
+	if s.minLength != nil && length < *s.minLength {
+		return fmt.Errorf("%s: expected at least %d characters, found %d", path, *s.minLength, length)
+	}
+	if s.maxLength != nil && length > *s.maxLength {
+		return fmt.Errorf("%s: expected at most %d characters, found %d", path, *s.maxLength, length)
+	}
+	if s.pattern != nil && !s.pattern.MatchString(str) {
+		return fmt.Errorf("%s: the value does not match %s", path, s.pattern)
+	}
+
+	return nil
+}
+
+// validateNumber checks the number keywords of the schema
+func (s *schema) validateNumber(n float64, path string) error {
+	if s.minimum != nil && n < *s.minimum {
+		return fmt.Errorf("%s: the value %v is lower than %v", path, n, *s.minimum)
+	}
+	if s.maximum != nil && n > *s.maximum {
+		return fmt.Errorf("%s: the value %v is greater than %v", path, n, *s.maximum)
+	}
+	if s.exclusiveMinimum != nil && n <= *s.exclusiveMinimum {
+		return fmt.Errorf("%s: the value %v must be greater than %v", path, n, *s.exclusiveMinimum)
+	}
+	if s.exclusiveMaximum != nil && n >= *s.exclusiveMaximum {
+		return fmt.Errorf("%s: the value %v must be lower than %v", path, n, *s.exclusiveMaximum)
+	}
+
+	return nil
+}
+
+// validateComposition checks the allOf, anyOf, oneOf and not keywords of the schema
+func (s *schema) validateComposition(v any, path string) error {
+	for _, sub := range s.allOf {
+		err := sub.validate(v, path)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(s.anyOf) > 0 {
+		matched := false
+		for _, sub := range s.anyOf {
+			if sub.validate(v, path) == nil {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: the value does not match any of the anyOf schemas", path)
+		}
+	}
+
+	if len(s.oneOf) > 0 {
+		matches := 0
+		for _, sub := range s.oneOf {
+			if sub.validate(v, path) == nil {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return fmt.Errorf("%s: the value must match exactly one of the oneOf schemas, matched %d", path, matches)
+		}
+	}
+
+	if s.not != nil && s.not.validate(v, path) == nil {
+		return fmt.Errorf("%s: the value must not match the not schema", path)
+	}
+
+	return nil
+}
+
+// decodeJSON decodes a json document, failing if there is any content after it.
+func decodeJSON(raw string, v any) error {
+	dec := json.NewDecoder(bytes.NewReader([]byte(raw)))
+	err := dec.Decode(v)
+	if err != nil {
+		return err
+	}
+	if dec.More() {
+		return fmt.Errorf("unexpected content after the json value")
+	}
+
+	return nil
+}
+
+// jsonType returns the json schema type name of a decoded json value
+func jsonType(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if val == math.Trunc(val) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+
+	return fmt.Sprintf("%T", v)
+}
+
+// matchesType checks if a decoded json value matches one of the json schema types
+func matchesType(v any, types []string) bool {
+	actual := jsonType(v)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaTypes parses the type keyword
+func schemaTypes(val any, path string) ([]string, error) {
+	if t, ok := val.(string); ok {
+		return []string{t}, nil
+	}
+
+	return schemaStrings(val, path)
+}
+
+// schemaStrings parses a keyword that must be an array of strings
+func schemaStrings(val any, path string) ([]string, error) {
+	list, ok := val.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: must be an array of strings", path)
+	}
+
+	res := make([]string, len(list))
+	for i, item := range list {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: must be an array of strings", path)
+		}
+		res[i] = s
+	}
+	return res, nil
+}
+
+// schemaNumber parses a keyword that must be a number
+func schemaNumber(val any, path string) (*float64, error) {
+	n, ok := val.(float64)
+	if !ok {
+		return nil, fmt.Errorf("%s: must be a number", path)
+	}
+	return &n, nil
+}
+
+// schemaInt parses a keyword that must be a non negative integer
+func schemaInt(val any, path string) (*int, error) {
+	n, ok := val.(float64)
+	if !ok || n < 0 || n != math.Trunc(n) {
+		return nil, fmt.Errorf("%s: must be a non negative integer", path)
+	}
+
+	i := int(n)
+	return &i, nil
+}
+
+// schemaList parses a keyword that must be a non empty array of schemas
+func schemaList(val any, path string) ([]*schema, error) {
+	list, ok := val.([]any)
+	if !ok || len(list) == 0 {
+		return nil, fmt.Errorf("%s: must be a non empty array of schemas", path)
+	}
+
+	res := make([]*schema, len(list))
+	for i, item := range list {
+		s, err := compileSchemaNode(item, fmt.Sprintf("%s/%d", path, i))
+		if err != nil {
+			return nil, err
+		}
+		res[i] = s
+	}
+	return res, nil
+}
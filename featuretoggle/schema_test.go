@@ -0,0 +1,142 @@
+package featuretoggle
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	t.Run("Should validate the supported keywords", func(t *testing.T) {
+		rawSchema := `{
+			"type": "object",
+			"required": ["gateway", "retries"],
+			"additionalProperties": false,
+			"properties": {
+				"gateway": {"type": "string", "enum": ["stripe", "adyen"]},
+				"retries": {"type": "integer", "minimum": 0, "maximum": 5},
+				"methods": {"type": "array", "items": {"type": "string", "minLength": 2}, "maxItems": 3},
+				"timeout": {"anyOf": [{"type": "string", "pattern": "^[0-9]+s$"}, {"type": "null"}]}
+			}
+		}`
+		sch, err := compileSchema(rawSchema)
+		if err != nil {
+			t.Fatalf("Should have compiled the schema, returned %v", err)
+		}
+
+		cases := map[string]bool{
+			`{"gateway": "stripe", "retries": 3}`:                                      true,
+			`{"gateway": "stripe", "retries": 3, "methods": ["pix", "card"]}`:          true,
+			`{"gateway": "stripe", "retries": 3, "timeout": "10s"}`:                    true,
+			`{"gateway": "stripe", "retries": 3, "timeout": null}`:                     true,
+			`{"gateway": "stirpe", "retries": 3}`:                                      false,
+			`{"gateway": "stripe"}`:                                                    false,
+			`{"gateway": "stripe", "retries": 3.5}`:                                    false,
+			`{"gateway": "stripe", "retries": 10}`:                                     false,
+			`{"gateway": "stripe", "retries": 3, "methods": ["p"]}`:                    false,
+			`{"gateway": "stripe", "retries": 3, "methods": ["a1", "b1", "c1", "d1"]}`: false,
+			`{"gateway": "stripe", "retries": 3, "timeout": "10m"}`:                    false,
+			`{"gateway": "stripe", "retries": 3, "other": true}`:                       false,
+			`["stripe"]`: false,
+		}
+		for payload, valid := range cases {
+			var v any
+			err := decodeJSON(payload, &v)
+			if err != nil {
+				t.Fatalf("Should have decoded the payload %s, returned %v", payload, err)
+			}
+
+			err = sch.validate(v, "#")
+			if (err == nil) != valid {
+				t.Errorf("Expected the payload %s to be valid=%v, validation returned %v", payload, valid, err)
+			}
+		}
+	})
+	t.Run("Should fail to compile invalid or unsupported schemas", func(t *testing.T) {
+		for _, rawSchema := range []string{
+			`not json`,
+			`"string"`,
+			`{"$ref": "#/$defs/config"}`,
+			`{"minItems": -1}`,
+			`{"anyOf": []}`,
+			`{"pattern": "[a-z"}`,
+			`{"type": "object", "minProperties": 1}`,
+			`{"type": "array", "uniqueItems": true}`,
+			`{"type": "array", "prefixItems": [{"type": "string"}]}`,
+			`{"properties": {"email": {"type": "string", "format": "email"}}}`,
+			`{"dependentRequired": {"gateway": ["retries"]}}`,
+		} {
+			_, err := compileSchema(rawSchema)
+			if err == nil {
+				t.Errorf("Should have failed to compile the schema %s", rawSchema)
+			}
+		}
+	})
+	t.Run("Should ignore the annotations", func(t *testing.T) {
+		_, err := compileSchema(`{
+			"$schema": "https://json-schema.org/draft/2020-12/schema",
+			"$comment": "the checkout limits",
+			"title": "limits",
+			"description": "the daily limits",
+			"type": "object",
+			"properties": {"daily": {"type": "integer", "default": 5, "examples": [5, 10], "deprecated": false}}
+		}`)
+		if err != nil {
+			t.Errorf("Should have compiled the schema with annotations, returned %v", err)
+		}
+	})
+	t.Run("Should reject the values of keys with unsupported keywords", func(t *testing.T) {
+		err := Check(map[string]string{
+			"checkout.limits":        `{"daily": 5}`,
+			"checkout.limits.type":   "json",
+			"checkout.limits.schema": `{"type": "object", "minProperties": 1}`,
+		}, "checkout.limits")
+		if err == nil {
+			t.Errorf("Should have rejected the schema with an unsupported keyword")
+		}
+	})
+	t.Run("Should reject payloads that do not match the key schema", func(t *testing.T) {
+		Mock(map[string]string{
+			"checkout.limits":        `{"daily": "five"}`,
+			"checkout.limits.type":   "json",
+			"checkout.limits.schema": `{"type": "object", "additionalProperties": {"type": "integer"}}`,
+		})
+		defer Reset()
+
+		defaultVal := map[string]any{"daily": float64(1)}
+		result := Get("checkout.limits", defaultVal)
+		if !reflect.DeepEqual(result, defaultVal) {
+			t.Errorf("Should have returned the default value if the payload did not match the schema, returned %v", result)
+		}
+
+		violations := Violations()
+		if len(violations) != 1 || violations[0].Constraint != "schema" || violations[0].KeptLastValid {
+			t.Errorf("Should have reported the schema violation, reported %+v", violations)
+		}
+	})
+	t.Run("Should keep the last valid value when a new payload does not match the key schema", func(t *testing.T) {
+		toggles := map[string]string{
+			"checkout.limits":        `{"daily": 5}`,
+			"checkout.limits.type":   "json",
+			"checkout.limits.schema": `{"type": "object", "additionalProperties": {"type": "integer"}}`,
+		}
+		Mock(toggles)
+		defer Reset()
+
+		Mock(map[string]string{
+			"checkout.limits":        `{"daily": "five"}`,
+			"checkout.limits.type":   "json",
+			"checkout.limits.schema": toggles["checkout.limits.schema"],
+		})
+
+		expected := map[string]int{"daily": 5}
+		result := Get("checkout.limits", map[string]int{})
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Should have kept the last valid value, returned %v", result)
+		}
+
+		violations := Violations()
+		if len(violations) != 1 || !violations[0].KeptLastValid {
+			t.Errorf("Should have reported the schema violation, reported %+v", violations)
+		}
+	})
+}
@@ -0,0 +1,205 @@
+package featuretoggle
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SyncStatus represents the state of the synchronization of the feature toggles with redis
+type SyncStatus struct {
+	// whether the feature toggles were loaded (by Init or Mock)
+	Initialized bool
+	// whether the last redis command, or the last subscription health check, succeeded
+	Connected bool
+	// whether the subscription to the feature toggle updates is active
+	Subscribed bool
+	// the last time the feature toggles were loaded from redis
+	LastSync time.Time
+	// the last time the feature toggles were confirmed to be up to date,
+	// by a successful sync or by a subscription health check
+	LastConfirmed time.Time
+	// the error of the last failed sync or subscription health check, cleared by the next successful sync
+	LastError error
+	// the number of feature toggles loaded, without their metadata fields
+	Keys int
+	// a hash of every key-value pair loaded, that changes whenever a feature toggle changes
+	Hash string
+	// the version of the feature toggles loaded (see VersionField), zero if they are not versioned
+	Version int64
+	// whether the feature toggles were not confirmed to be up to date for longer than Config.MaxStaleness
+	Stale bool
+}
+
+var (
+	// the synchronization state, updated by the cache rebuilds and the subscription
+	syncMu    sync.Mutex
+	syncState SyncStatus
+	// how long the feature toggles may go without being confirmed up to date, zero if never stale
+	maxStaleness time.Duration
+	// closes the staleness checker started by Init, if any
+	stopStaleness chan struct{}
+)
+
+// Status returns the state of the synchronization of the feature toggles with redis.
+func Status() SyncStatus {
+	syncMu.Lock()
+	st, max := syncState, maxStaleness
+	syncMu.Unlock()
+
+	s := current()
+	st.Initialized = s != nil
+	st.Keys = s.keys()
+	if s != nil {
+		st.Hash, st.Version = s.hash, s.version
+	}
+	st.Stale = isStale(st, max, time.Now())
+
+	return st
+}
+
+// Ping checks the connection with redis, waiting at most until the context is done.
+// returns ErrNotInitialized if the library was not initiated with Init.
+func Ping(ctx context.Context) error {
+	cl := client
+	if cl == nil {
+		return ErrNotInitialized
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cl.ping()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// isStale checks if the feature toggles were not confirmed to be up to date for longer than the max staleness.
+// Feature toggles that are not loaded from redis (like mocked ones) are never stale.
+func isStale(st SyncStatus, max time.Duration, now time.Time) bool {
+	if max <= 0 || st.LastConfirmed.IsZero() {
+		return false
+	}
+
+	return now.Sub(st.LastConfirmed) > max
+}
+
+// synced records the result of a sync with redis.
+func synced(err error) {
+	syncMu.Lock()
+	defer syncMu.Unlock()
+
+	syncState.Connected = err == nil
+	syncState.LastError = err
+	if err == nil {
+		syncState.LastSync = time.Now()
+		syncState.LastConfirmed = syncState.LastSync
+	}
+}
+
+// subscriptionChanged records a change of the subscription state, with the error that caused it, if any.
+// An active subscription confirms the feature toggles are up to date, since every update would have been received.
+func subscriptionChanged(subscribed bool, err error) {
+	syncMu.Lock()
+	defer syncMu.Unlock()
+
+	syncState.Subscribed = subscribed
+	syncState.Connected = err == nil
+	if err != nil {
+		syncState.LastError = err
+		return
+	}
+	if subscribed {
+		syncState.LastConfirmed = time.Now()
+	}
+}
+
+// resetStatus resets the synchronization state, and stops the staleness checker, if any.
+func resetStatus() {
+	syncMu.Lock()
+	defer syncMu.Unlock()
+
+	syncState = SyncStatus{}
+	maxStaleness = 0
+	if stopStaleness != nil {
+		close(stopStaleness)
+		stopStaleness = nil
+	}
+}
+
+// watchStaleness checks the staleness of the feature toggles periodically,
+// emitting an EventStale when they become stale, and an EventFresh when they are up to date again.
+func watchStaleness(max time.Duration) {
+	syncMu.Lock()
+	maxStaleness = max
+	stop := make(chan struct{})
+	stopStaleness = stop
+	syncMu.Unlock()
+
+	interval := max / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		stale := false
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				stale = checkStaleness(stale, now)
+			}
+		}
+	}()
+}
+
+// checkStaleness emits an event if the staleness of the feature toggles changed.
+// returns whether the feature toggles are stale.
+func checkStaleness(wasStale bool, now time.Time) bool {
+	syncMu.Lock()
+	st, max := syncState, maxStaleness
+	syncMu.Unlock()
+
+	stale := isStale(st, max, now)
+	switch {
+	case stale && !wasStale:
+		logError(context.Background(), "The feature toggles are stale", "last_confirmed", st.LastConfirmed.String())
+		emit(Event{Type: EventStale, Err: st.LastError, Duration: now.Sub(st.LastConfirmed)})
+	case !stale && wasStale:
+		logInfo(context.Background(), "The feature toggles are up to date again")
+		emit(Event{Type: EventFresh})
+	}
+
+	return stale
+}
+
+// hashRaw returns a hash of every key-value pair of the raw feature toggles, independent of their order.
+func hashRaw(raw map[string]string) string {
+	keys := make([]string, 0, len(raw))
+	for k := range raw {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write([]byte(raw[k]))
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
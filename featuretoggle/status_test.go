@@ -0,0 +1,118 @@
+package featuretoggle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStatus(t *testing.T) {
+	t.Run("Should report the feature toggles loaded", func(t *testing.T) {
+		Mock(map[string]string{
+			"MyBool":      "true",
+			"MyBool.type": "boolean",
+			"MyString":    "value",
+		})
+		defer Reset()
+
+		st := Status()
+		if !st.Initialized || st.Keys != 2 || st.Hash == "" || st.Stale {
+			t.Errorf("Should have reported the mocked feature toggles, reported %+v", st)
+		}
+
+		hash := st.Hash
+		Mock(map[string]string{
+			"MyBool":      "false",
+			"MyBool.type": "boolean",
+			"MyString":    "value",
+		})
+		if Status().Hash == hash {
+			t.Errorf("Should have changed the hash when a feature toggle changed")
+		}
+
+		Mock(map[string]string{
+			"MyString":    "value",
+			"MyBool.type": "boolean",
+			"MyBool":      "false",
+		})
+		if Status().Hash != hashRaw(map[string]string{"MyBool": "false", "MyBool.type": "boolean", "MyString": "value"}) {
+			t.Errorf("Should not have changed the hash when the feature toggles did not change")
+		}
+	})
+	t.Run("Should report the syncs with redis", func(t *testing.T) {
+		defer Reset()
+		fake := &fakeRedis{toggles: map[string]string{"MyBool": "true", "MyBool.type": "boolean"}}
+		client, serviceName = fake, "MyService"
+
+		before := time.Now()
+		_ = buildCache()
+		st := Status()
+		if !st.Connected || st.LastSync.Before(before) || st.LastError != nil {
+			t.Errorf("Should have reported the successful sync, reported %+v", st)
+		}
+
+		fake.err = errors.New("connection refused")
+		_ = buildCache()
+		failed := Status()
+		if failed.Connected || failed.LastSync != st.LastSync || failed.LastError == nil {
+			t.Errorf("Should have reported the failed sync, keeping the last successful sync time, reported %+v", failed)
+		}
+	})
+	t.Run("Should report and emit the staleness", func(t *testing.T) {
+		defer Reset()
+		fake := &fakeRedis{toggles: map[string]string{"MyBool": "true", "MyBool.type": "boolean"}}
+		client, serviceName = fake, "MyService"
+		_ = buildCache()
+
+		syncMu.Lock()
+		maxStaleness = time.Minute
+		syncMu.Unlock()
+
+		var events []Event
+		unregister := OnEvent(func(e Event) {
+			if e.Type == EventStale || e.Type == EventFresh {
+				events = append(events, e)
+			}
+		})
+		defer unregister()
+
+		now := time.Now()
+		stale := checkStaleness(false, now)
+		if stale || Status().Stale {
+			t.Errorf("Should not have been stale right after the sync")
+		}
+
+		stale = checkStaleness(stale, now.Add(2*time.Minute))
+		stale = checkStaleness(stale, now.Add(3*time.Minute))
+		if !stale {
+			t.Errorf("Should have been stale after the max staleness")
+		}
+
+		_ = buildCache()
+		checkStaleness(stale, time.Now())
+
+		if len(events) != 2 || events[0].Type != EventStale || events[1].Type != EventFresh {
+			t.Errorf("Should have emitted the stale and fresh events once, emitted %+v", events)
+		}
+	})
+}
+
+func TestPing(t *testing.T) {
+	defer Reset()
+
+	if err := Ping(context.Background()); !errors.Is(err, ErrNotInitialized) {
+		t.Errorf("Should have returned ErrNotInitialized without a redis client, returned %v", err)
+	}
+
+	fake := &fakeRedis{}
+	client = fake
+	if err := Ping(context.Background()); err != nil {
+		t.Errorf("Should have pinged redis, returned %v", err)
+	}
+
+	fake.err = errors.New("connection refused")
+	if err := Ping(context.Background()); err != fake.err {
+		t.Errorf("Should have returned the ping error, returned %v", err)
+	}
+}
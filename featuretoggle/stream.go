@@ -0,0 +1,194 @@
+package featuretoggle
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+const (
+	// the field of the change stream entries with the version of the feature toggles after the change
+	changeVersion = "version"
+	// the prefix of the change stream entry fields set by the change (ex.: "set:MyBool")
+	changeSet = "set:"
+	// the prefix of the change stream entry fields deleted by the change (ex.: "del:MyBool")
+	changeDel = "del:"
+
+	// how many changes are read from the stream at once
+	changeBatch = 100
+	// how long the stream is read before checking again, when there are no changes
+	changeBlock = 5 * time.Second
+	// the longest wait before reading the stream again after a failure
+	changeMaxRetry = 30 * time.Second
+)
+
+// the first wait before reading the stream again after a failure, doubled on every consecutive failure
+var changeRetry = time.Second
+
+// ChangeStream returns the name of the redis stream where the changes of the feature toggles of the service are appended,
+// read by the library in change stream mode (see Config.ChangeStream).
+func ChangeStream(service string) string {
+	return service + ":changes"
+}
+
+// ChangeValues returns the values of the change stream entry of a write of the feature toggles
+// (see ChangeStream), with the version of the feature toggles after the write (see VersionField),
+// and the fields set and deleted by the write.
+//
+// The entry must be appended in the same transaction as the write, with the version incremented by one,
+// so that the services can find the changes they missed.
+func ChangeValues(version int64, set map[string]string, del []string) map[string]interface{} {
+	values := make(map[string]interface{}, len(set)+len(del)+1)
+	values[changeVersion] = strconv.FormatInt(version, 10)
+	for field, val := range set {
+		if field != VersionField {
+			values[changeSet+field] = val
+		}
+	}
+	for _, field := range del {
+		values[changeDel+field] = ""
+	}
+	return values
+}
+
+// change represents an entry of the change stream
+type change struct {
+	version int64
+	set     map[string]string
+	del     []string
+}
+
+// parseChange parses the values of an entry of the change stream.
+func parseChange(values map[string]interface{}) (change, error) {
+	c := change{set: map[string]string{}}
+
+	v, _ := values[changeVersion].(string)
+	version, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return c, fmt.Errorf("invalid version %q", v)
+	}
+	c.version = version
+
+	for field, val := range values {
+		if key, ok := strings.CutPrefix(field, changeSet); ok {
+			c.set[key] = fmt.Sprint(val)
+		} else if key, ok := strings.CutPrefix(field, changeDel); ok {
+			c.del = append(c.del, key)
+		}
+	}
+	return c, nil
+}
+
+// consumeChanges reads the change stream of the service from the id, forever,
+// applying the changes to the cache (see applyChanges).
+func consumeChanges(cl redisClient, id string) {
+	stream := ChangeStream(serviceName)
+	retry := changeRetry
+	backoff := func() {
+		time.Sleep(retry)
+		retry = min(2*retry, changeMaxRetry)
+	}
+
+	for {
+		start := time.Now()
+		msgs, err := cl.xread(stream, id, changeBatch, changeBlock)
+		if err != nil || len(msgs) > 0 {
+			emit(Event{Type: EventRedisCommand, Key: stream, Method: "XREAD", Duration: time.Since(start), Err: err})
+		}
+		if err != nil {
+			if err.Error() == "redis: client is closed" {
+				logInfo(context.Background(), "The feature toggle change stream reader was closed")
+				subscriptionChanged(false, nil)
+				return
+			}
+
+			subscriptionChanged(false, err)
+			backoff()
+			continue
+		}
+		subscriptionChanged(true, nil)
+
+		if len(msgs) == 0 {
+			retry = changeRetry
+			continue
+		}
+		err = applyChanges(msgs)
+		if err != nil {
+			// the same changes are read again
+			logError(context.Background(), "Failed to apply the feature toggle changes", "error", err.Error())
+			backoff()
+			continue
+		}
+		retry = changeRetry
+		id = msgs[len(msgs)-1].ID
+	}
+}
+
+// applyChanges applies the changes read from the stream to the cache, without reading the whole hash.
+//
+// The changes already loaded (with a version not greater than the version in use) are skipped,
+// and the whole hash is read again (with HGETALL) if any change was missed, like when the stream is trimmed,
+// or when an entry is invalid.
+//
+// returns an error if the hash could not be read again.
+func applyChanges(msgs []redis.XMessage) error {
+	start := time.Now()
+
+	s := current()
+	if s == nil {
+		return buildCache()
+	}
+
+	var raw map[string]string
+	version := s.version
+	for _, msg := range msgs {
+		c, err := parseChange(msg.Values)
+		if err == nil && c.version <= version {
+			continue
+		}
+		if err == nil && c.version != version+1 {
+			err = fmt.Errorf("expected the version %d, found %d", version+1, c.version)
+		}
+		if err != nil {
+			logInfo(context.Background(), "[Feature Toggle] Changes were missed, the feature toggles will be reloaded",
+				"id", msg.ID,
+				"error", err.Error(),
+			)
+
+			err = buildCache()
+			if err != nil {
+				return err
+			}
+			s, raw = current(), nil
+			version = s.version
+			continue
+		}
+
+		if raw == nil {
+			raw = make(map[string]string, len(s.raw)+len(c.set))
+			for k, v := range s.raw {
+				raw[k] = v
+			}
+		}
+		for _, field := range c.del {
+			delete(raw, field)
+		}
+		for field, val := range c.set {
+			raw[field] = val
+		}
+		version = c.version
+		raw[VersionField] = strconv.FormatInt(version, 10)
+	}
+
+	if raw == nil {
+		return nil
+	}
+	store(raw)
+	synced(nil)
+	emit(Event{Type: EventCacheRebuilt, Duration: time.Since(start), Keys: current().keys()})
+	return nil
+}
@@ -0,0 +1,145 @@
+package featuretoggle
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+func TestChangeValues(t *testing.T) {
+	values := ChangeValues(3, map[string]string{"MyBool": "true", VersionField: "3"}, []string{"Old"})
+
+	expected := map[string]interface{}{"version": "3", "set:MyBool": "true", "del:Old": ""}
+	if !reflect.DeepEqual(values, expected) {
+		t.Fatalf("Expected the values %v, returned %v", expected, values)
+	}
+
+	c, err := parseChange(values)
+	if err != nil {
+		t.Fatalf("Should have parsed the change, returned %v", err)
+	}
+	if c.version != 3 || !reflect.DeepEqual(c.set, map[string]string{"MyBool": "true"}) || !reflect.DeepEqual(c.del, []string{"Old"}) {
+		t.Errorf("Should have parsed the change, parsed %+v", c)
+	}
+
+	_, err = parseChange(map[string]interface{}{"set:MyBool": "true"})
+	if err == nil {
+		t.Errorf("Should have failed to parse a change without version")
+	}
+}
+
+func TestApplyChanges(t *testing.T) {
+	setup := func(t *testing.T) *fakeRedis {
+		fake := &fakeRedis{toggles: map[string]string{
+			"MyBool":      "false",
+			"MyBool.type": "boolean",
+			"Old":         "value",
+			VersionField:  "1",
+		}}
+		client, serviceName = fake, "MyService"
+		if err := buildCache(); err != nil {
+			t.Fatalf("Should have loaded the feature toggles, returned %v", err)
+		}
+		fake.calls = 0
+		return fake
+	}
+	entry := func(id string, version int64, set map[string]string, del ...string) redis.XMessage {
+		return redis.XMessage{ID: id, Values: ChangeValues(version, set, del)}
+	}
+
+	t.Run("Should apply the changes without reading the whole hash", func(t *testing.T) {
+		defer Reset()
+		fake := setup(t)
+
+		err := applyChanges([]redis.XMessage{
+			entry("1-0", 1, map[string]string{"Old": "loaded by the HGETALL"}),
+			entry("2-0", 2, map[string]string{"MyBool": "true"}, "Old"),
+			entry("3-0", 3, map[string]string{"MyString": "value", "MyString.type": "string"}),
+		})
+		if err != nil {
+			t.Fatalf("Should have applied the changes, returned %v", err)
+		}
+
+		if fake.calls != 0 {
+			t.Errorf("Should not have read the whole hash, called HGETALL %d times", fake.calls)
+		}
+		if !IsEnabled("MyBool", false) || GetString("MyString", "") != "value" {
+			t.Errorf("Should have applied the changes, found %v", Snapshot())
+		}
+		if _, ok := Snapshot()["Old"]; ok {
+			t.Errorf("Should have deleted the field")
+		}
+		if s := Status(); s.Version != 3 {
+			t.Errorf("Should have updated the version to 3, found %d", s.Version)
+		}
+	})
+	t.Run("Should read the whole hash when changes were missed", func(t *testing.T) {
+		defer Reset()
+		fake := setup(t)
+		fake.toggles = map[string]string{"MyBool": "true", "MyBool.type": "boolean", VersionField: "3"}
+
+		// the entry of the version 2 was trimmed
+		err := applyChanges([]redis.XMessage{
+			entry("3-0", 3, map[string]string{"MyBool": "true"}),
+			entry("4-0", 4, map[string]string{"MyString": "value", "MyString.type": "string"}),
+		})
+		if err != nil {
+			t.Fatalf("Should have applied the changes, returned %v", err)
+		}
+
+		if fake.calls != 1 {
+			t.Errorf("Should have read the whole hash once, called HGETALL %d times", fake.calls)
+		}
+		if !IsEnabled("MyBool", false) || GetString("MyString", "") != "value" {
+			t.Errorf("Should have applied the changes after the reload, found %v", Snapshot())
+		}
+		if s := Status(); s.Version != 4 {
+			t.Errorf("Should have updated the version to 4, found %d", s.Version)
+		}
+	})
+	t.Run("Should read the whole hash when an entry is invalid", func(t *testing.T) {
+		defer Reset()
+		fake := setup(t)
+
+		err := applyChanges([]redis.XMessage{{ID: "2-0", Values: map[string]interface{}{"set:MyBool": "true"}}})
+		if err != nil {
+			t.Fatalf("Should have reloaded the feature toggles, returned %v", err)
+		}
+		if fake.calls != 1 {
+			t.Errorf("Should have read the whole hash once, called HGETALL %d times", fake.calls)
+		}
+	})
+}
+
+func TestConsumeChanges(t *testing.T) {
+	t.Run("Should mark the subscription down when the stream can not be read", func(t *testing.T) {
+		defer Reset()
+		defer func(retry time.Duration) { changeRetry = retry }(changeRetry)
+		changeRetry = time.Millisecond
+
+		fake := &fakeRedis{toggles: map[string]string{}, xreadErrs: make(chan error)}
+		client, serviceName = fake, "MyService"
+		subscriptionChanged(true, nil)
+
+		done := make(chan struct{})
+		go func() {
+			consumeChanges(fake, "0")
+			close(done)
+		}()
+
+		refused := errors.New("connection refused")
+		fake.xreadErrs <- refused
+		// the next read only starts after the failure is recorded
+		fake.xreadErrs <- refused
+
+		if s := Status(); s.Subscribed || s.Connected || s.LastError != refused {
+			t.Errorf("Should have marked the subscription down, status %+v", s)
+		}
+
+		fake.xreadErrs <- errors.New("redis: client is closed")
+		<-done
+	})
+}
@@ -0,0 +1,196 @@
+package featuretoggle
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ValidationProblem represents the kind of problem found when validating a declared flag
+type ValidationProblem string
+
+const (
+	// ProblemMissingKey means the key was not found, or its value is empty
+	ProblemMissingKey ValidationProblem = "missing key"
+	// ProblemMissingType means the "<key>.type" field was not found, or is empty
+	ProblemMissingType ValidationProblem = "missing type"
+	// ProblemTypeMismatch means the "<key>.type" field does not match the type declared for the flag
+	ProblemTypeMismatch ValidationProblem = "type mismatch"
+	// ProblemInvalidValue means the value could not be parsed into the type declared for the flag
+	ProblemInvalidValue ValidationProblem = "invalid value"
+	// ProblemConstraintViolation means the value does not satisfy the constraints declared in its metadata fields
+	ProblemConstraintViolation ValidationProblem = "constraint violation"
+)
+
+// ValidationError describes a declared flag that does not match the feature toggles loaded from redis.
+type ValidationError struct {
+	// the feature toggle key
+	Key string
+	// the kind of problem found
+	Problem ValidationProblem
+	// the type declared for the flag
+	ExpectedType string
+	// the type found in the "<key>.type" field
+	FoundType string
+	// the parsing error for ProblemInvalidValue, or the *Violation for ProblemConstraintViolation
+	Err error
+}
+
+// Error returns the validation error message
+func (e *ValidationError) Error() string {
+	switch e.Problem {
+	case ProblemTypeMismatch:
+		return fmt.Sprintf("feature toggle %s: %s, expected %s but found %s", e.Key, e.Problem, e.ExpectedType, e.FoundType)
+	case ProblemInvalidValue, ProblemConstraintViolation:
+		return fmt.Sprintf("feature toggle %s: %s for type %s: %s", e.Key, e.Problem, e.ExpectedType, e.Err)
+	}
+
+	return fmt.Sprintf("feature toggle %s: %s", e.Key, e.Problem)
+}
+
+// Unwrap returns the parsing error, if any
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches the validation error with the sentinel error of its problem (ex.: ErrNotFound for ProblemMissingKey)
+func (e *ValidationError) Is(target error) bool {
+	switch e.Problem {
+	case ProblemMissingKey:
+		return target == ErrNotFound
+	case ProblemMissingType:
+		return target == ErrTypeMissing
+	case ProblemTypeMismatch:
+		return target == ErrTypeMismatch
+	case ProblemInvalidValue:
+		return target == ErrParse
+	case ProblemConstraintViolation:
+		return target == ErrConstraintViolation
+	}
+
+	return false
+}
+
+// ValidationErrors represents all of the problems found when validating the declared flags
+type ValidationErrors []*ValidationError
+
+// Error returns all of the validation error messages
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+
+	return fmt.Sprintf("%d invalid feature toggles: %s", len(errs), strings.Join(msgs, "; "))
+}
+
+/*
+Validate checks every flag declared through the typed handle constructors (Bool, Number, String and JSON)
+against the feature toggles loaded from redis.
+
+returns a ValidationErrors with one entry for each flag that:
+
+- was not found, or has an empty value;
+
+- has no "<key>.type" field, or the field is empty;
+
+- has a "<key>.type" field different from the declared type;
+
+- has a value that could not be parsed into the declared type;
+
+- has a value that violates its constraints (see Violations).
+
+returns nil if every declared flag is valid.
+*/
+func Validate() error {
+	s := current()
+	if s == nil {
+		return fmt.Errorf("Failed to validate the feature toggles, the library was not initiated")
+	}
+
+	var errs ValidationErrors
+	for _, def := range flagDefs() {
+		info := def.info
+
+		var verr *ValidationError
+		e, ok := s.entry(info.Key)
+		if !ok || e.blank {
+			verr = &ValidationError{Problem: ProblemMissingKey}
+		} else {
+			verr = def.check(e)
+		}
+
+		if verr == nil && e.violation != nil {
+			verr = &ValidationError{Problem: ProblemConstraintViolation, FoundType: e.typ, Err: e.violation}
+		}
+
+		if verr != nil {
+			verr.Key = info.Key
+			verr.ExpectedType = info.Type
+			errs = append(errs, verr)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// checkType checks if the entry declares one of the expected types
+func checkType(e *entry, expected ...string) *ValidationError {
+	if !e.hasType {
+		return &ValidationError{Problem: ProblemMissingType}
+	}
+
+	for _, t := range expected {
+		if e.typ == t {
+			return nil
+		}
+	}
+	return &ValidationError{Problem: ProblemTypeMismatch, FoundType: e.typ}
+}
+
+// checkBoolean checks if the entry is a valid boolean feature toggle
+func checkBoolean(e *entry) *ValidationError {
+	if verr := checkType(e, "boolean"); verr != nil {
+		return verr
+	}
+
+	_, err := strconv.ParseBool(e.val)
+	if err != nil {
+		return &ValidationError{Problem: ProblemInvalidValue, FoundType: e.typ, Err: err}
+	}
+	return nil
+}
+
+// checkNumber checks if the entry is a valid number feature toggle
+func checkNumber(e *entry) *ValidationError {
+	if verr := checkType(e, "number", "integer"); verr != nil {
+		return verr
+	}
+
+	_, err := strconv.ParseFloat(e.val, 64)
+	if err != nil {
+		return &ValidationError{Problem: ProblemInvalidValue, FoundType: e.typ, Err: err}
+	}
+	return nil
+}
+
+// checkString checks if the entry is a valid string feature toggle
+func checkString(e *entry) *ValidationError {
+	return checkType(e, "string", "secret")
+}
+
+// checkDecode checks if the entry is a json feature toggle, with a value that can be decoded into the type T the same way Get does
+func checkDecode[T any](e *entry) *ValidationError {
+	if verr := checkType(e, "json"); verr != nil {
+		return verr
+	}
+
+	d := decode[T](e)
+	if d.err != nil {
+		return &ValidationError{Problem: ProblemInvalidValue, FoundType: e.typ, Err: d.err}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package featuretoggle
+
+import (
+	"errors"
+	"testing"
+)
+
+// withRegistry runs fn with an empty flag registry, restoring the previous one afterwards
+func withRegistry(fn func()) {
+	registryMu.Lock()
+	previous := registry
+	registry = map[string]flagDef{}
+	registryMu.Unlock()
+
+	defer func() {
+		registryMu.Lock()
+		registry = previous
+		registryMu.Unlock()
+	}()
+
+	fn()
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("Should return an error if the library was not initiated", func(t *testing.T) {
+		Mock(nil)
+		defer Reset()
+
+		if Validate() == nil {
+			t.Errorf("Should have returned an error if the library was not initiated")
+		}
+	})
+	t.Run("Should return nil if every declared flag is valid", func(t *testing.T) {
+		withRegistry(func() {
+			Mock(map[string]string{
+				"valid.bool":        "true",
+				"valid.bool.type":   "boolean",
+				"valid.number":      "10",
+				"valid.number.type": "number",
+				"valid.json":        `{"daily": 5}`,
+				"valid.json.type":   "json",
+			})
+			defer Reset()
+
+			Bool("valid.bool", false)
+			Number("valid.number", 0)
+			JSON("valid.json", map[string]int{})
+
+			err := Validate()
+			if err != nil {
+				t.Errorf("Should not have returned an error, returned %v", err)
+			}
+		})
+	})
+	t.Run("Should report every invalid declared flag", func(t *testing.T) {
+		withRegistry(func() {
+			Mock(map[string]string{
+				"invalid.notype":             "true",
+				"invalid.mismatch":           "10",
+				"invalid.mismatch.type":      "string",
+				"invalid.parse":              "ten",
+				"invalid.parse.type":         "number",
+				"invalid.json":               `"not a map"`,
+				"invalid.json.type":          "json",
+				"invalid.json.notype":        `{"daily": 5}`,
+				"invalid.json.mismatch":      `{"daily": 5}`,
+				"invalid.json.mismatch.type": "string",
+				"invalid.scheduled":          "true",
+				"invalid.scheduled.type":     "boolean",
+				"invalid.scheduled.schedule": scheduleOf("UTC", "2000-01-01T00:00:00", `"yes"`),
+			})
+			defer Reset()
+
+			Bool("invalid.missing", false)
+			Bool("invalid.notype", false)
+			Number("invalid.mismatch", 0)
+			Number("invalid.parse", 0)
+			JSON("invalid.json", map[string]int{})
+			JSON("invalid.json.notype", map[string]int{})
+			JSON("invalid.json.mismatch", map[string]int{})
+			Bool("invalid.scheduled", false)
+
+			err := Validate()
+
+			var errs ValidationErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("Should have returned the validation errors, returned %v", err)
+			}
+
+			expected := map[string]ValidationProblem{
+				"invalid.json":          ProblemInvalidValue,
+				"invalid.json.notype":   ProblemMissingType,
+				"invalid.json.mismatch": ProblemTypeMismatch,
+				"invalid.scheduled":     ProblemInvalidValue,
+				"invalid.mismatch":      ProblemTypeMismatch,
+				"invalid.missing":       ProblemMissingKey,
+				"invalid.notype":        ProblemMissingType,
+				"invalid.parse":         ProblemInvalidValue,
+			}
+			if len(errs) != len(expected) {
+				t.Fatalf("Should have returned %d validation errors, returned %v", len(expected), errs)
+			}
+			for _, verr := range errs {
+				if expected[verr.Key] != verr.Problem {
+					t.Errorf("Should have reported %s for key %s, reported %s", expected[verr.Key], verr.Key, verr.Problem)
+				}
+			}
+		})
+	})
+}
@@ -0,0 +1,39 @@
+package featuretoggle
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// VersionField is the field of the service hash with the version of the feature toggles,
+// changed by every write made through the admin package, in the same transaction (see Config.Versioned).
+const VersionField = "__version"
+
+// whether the cache is rebuilt only when the version of the feature toggles changes
+var versioned atomic.Bool
+
+// versionOf returns the version of the raw feature toggles, zero if it was not found or is invalid.
+func versionOf(raw map[string]string) int64 {
+	v, _ := strconv.ParseInt(raw[VersionField], 10, 64)
+	return v
+}
+
+// versionChanged checks, with a single HGET, if the version of the feature toggles in redis differs from the version in use.
+// Assumes the version changed if it could not be checked, so the cache is rebuilt anyway.
+func versionChanged() bool {
+	s := current()
+	if s == nil {
+		return true
+	}
+
+	start := time.Now()
+	val, err := client.hget(serviceName, VersionField)
+	emit(Event{Type: EventRedisCommand, Key: serviceName, Method: "HGET", Duration: time.Since(start), Err: err})
+	if err != nil {
+		return true
+	}
+
+	v, _ := strconv.ParseInt(val, 10, 64)
+	return v != s.version
+}
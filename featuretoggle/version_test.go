@@ -0,0 +1,57 @@
+package featuretoggle
+
+import (
+	"testing"
+
+	"github.com/go-redis/redis"
+)
+
+func TestVersioned(t *testing.T) {
+	t.Run("Should rebuild the cache only when the version changes", func(t *testing.T) {
+		defer Reset()
+		fake := &fakeRedis{toggles: map[string]string{
+			"MyBool":      "true",
+			"MyBool.type": "boolean",
+			VersionField:  "1",
+		}}
+		client, serviceName = fake, "MyService"
+		versioned.Store(true)
+
+		err := buildCache()
+		if err != nil {
+			t.Fatalf("Should have built the cache, returned %v", err)
+		}
+
+		update := &redis.Message{Channel: "__keyspace@0__:MyService", Payload: "hset"}
+		fake.toggles = map[string]string{"MyBool": "false", "MyBool.type": "boolean", VersionField: "1"}
+		for i := 0; i < 5; i++ {
+			handleUpdate(update)
+		}
+		if fake.calls != 1 || fake.hgets != 5 || !IsEnabled("MyBool", false) {
+			t.Errorf("Should not have rebuilt the cache before the version changed, called hgetall %d times", fake.calls)
+		}
+
+		fake.toggles = map[string]string{"MyBool": "false", "MyBool.type": "boolean", VersionField: "2"}
+		handleUpdate(update)
+		handleUpdate(update)
+		if fake.calls != 2 || IsEnabled("MyBool", true) {
+			t.Errorf("Should have rebuilt the cache once when the version changed, called hgetall %d times", fake.calls)
+		}
+
+		if st := Status(); st.Version != 2 || st.Keys != 1 {
+			t.Errorf("Should have returned the version without counting it as a key, returned %+v", st)
+		}
+	})
+	t.Run("Should rebuild the cache on every update when not versioned", func(t *testing.T) {
+		defer Reset()
+		fake := &fakeRedis{toggles: map[string]string{VersionField: "1"}}
+		client, serviceName = fake, "MyService"
+
+		update := &redis.Message{Channel: "__keyspace@0__:MyService", Payload: "hset"}
+		handleUpdate(update)
+		handleUpdate(update)
+		if fake.calls != 2 || fake.hgets != 0 {
+			t.Errorf("Should have rebuilt the cache on every update, called hgetall %d times", fake.calls)
+		}
+	})
+}
@@ -0,0 +1,168 @@
+// Package health provides health checks of the feature toggle library,
+// as http.Handlers for liveness and readiness probes, and as a plain Check function.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/delivery-much/dm-go-ft/featuretoggle"
+)
+
+// DefaultTimeout is the default timeout of the redis ping
+const DefaultTimeout = 2 * time.Second
+
+// ErrDegraded means the feature toggles are loaded, but redis can not be reached,
+// the subscription to the updates is down, or the feature toggles are stale
+var ErrDegraded = errors.New("the feature toggle backend is degraded")
+
+// Config represents the health check configuration
+type Config struct {
+	// ServeDegraded keeps the readiness while the backend is degraded (see ErrDegraded),
+	// since the feature toggles already loaded keep being served. By default, a degraded backend fails the readiness.
+	ServeDegraded bool
+	// Timeout is the timeout of the redis ping, uses DefaultTimeout if zero.
+	Timeout time.Duration
+}
+
+// Checker checks the health of the feature toggle library
+type Checker struct {
+	c Config
+	// pings redis, replaced in the tests
+	ping func(ctx context.Context) error
+}
+
+// Report represents the result of the health checks, written by the handlers as json
+type Report struct {
+	// "ok", "degraded" or "fail"
+	Status string `json:"status"`
+	// the result of each check ("ok" or the error message), by name:
+	// "initialized", "redis", "subscription" and "staleness"
+	Checks map[string]string `json:"checks"`
+	// the status of the synchronization with redis
+	LastSync time.Time `json:"last_sync"`
+	Keys     int       `json:"keys"`
+	Hash     string    `json:"hash"`
+
+	// the error returned by Check
+	err error
+}
+
+// New creates a Checker with the configuration.
+func New(c Config) *Checker {
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+
+	return &Checker{c, featuretoggle.Ping}
+}
+
+// Check checks the health of the feature toggle library with the default configuration,
+// same as New(Config{}).Check(ctx).
+func Check(ctx context.Context) error {
+	return New(Config{}).Check(ctx)
+}
+
+// Check checks if the feature toggle library is ready to serve the feature toggles.
+//
+// returns featuretoggle.ErrNotInitialized if the feature toggles were not loaded,
+// or an error matching ErrDegraded if the backend is degraded and the config does not ServeDegraded.
+func (c *Checker) Check(ctx context.Context) error {
+	return c.Report(ctx).err
+}
+
+// Report runs every health check.
+func (c *Checker) Report(ctx context.Context) Report {
+	return c.report(ctx, true)
+}
+
+// report runs the health checks, pinging redis only if ping is true.
+// Without the ping, the report is built only from the synchronization status (see featuretoggle.Status).
+func (c *Checker) report(ctx context.Context, ping bool) Report {
+	st := featuretoggle.Status()
+	r := Report{
+		Status:   "ok",
+		Checks:   map[string]string{},
+		LastSync: st.LastSync,
+		Keys:     st.Keys,
+		Hash:     st.Hash,
+	}
+
+	if !st.Initialized {
+		r.Status = "fail"
+		r.Checks["initialized"] = featuretoggle.ErrNotInitialized.Error()
+		r.err = featuretoggle.ErrNotInitialized
+		return r
+	}
+	r.Checks["initialized"] = "ok"
+
+	var problems []error
+	check := func(name string, err error) {
+		if err == nil {
+			r.Checks[name] = "ok"
+			return
+		}
+		r.Checks[name] = err.Error()
+		problems = append(problems, fmt.Errorf("%s: %w", name, err))
+	}
+
+	if ping {
+		ctx, cancel := context.WithTimeout(ctx, c.c.Timeout)
+		defer cancel()
+		check("redis", c.ping(ctx))
+	}
+	if !st.Subscribed {
+		check("subscription", errors.New("the subscription to the feature toggle updates is down"))
+	} else {
+		check("subscription", nil)
+	}
+	if st.Stale {
+		check("staleness", fmt.Errorf("the feature toggles were last confirmed at %s", st.LastConfirmed.Format(time.RFC3339)))
+	} else {
+		check("staleness", nil)
+	}
+
+	if len(problems) == 0 {
+		return r
+	}
+
+	r.Status = "degraded"
+	if !c.c.ServeDegraded {
+		r.err = fmt.Errorf("%w: %w", ErrDegraded, errors.Join(problems...))
+	}
+	return r
+}
+
+// Readiness returns a handler for readiness probes, that responds with the health Report,
+// and fails (with status 503) for the same reasons Check returns an error.
+func (c *Checker) Readiness() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r := c.Report(req.Context())
+
+		code := http.StatusOK
+		if r.err != nil {
+			code = http.StatusServiceUnavailable
+		}
+		write(w, code, r)
+	})
+}
+
+// Liveness returns a handler for liveness probes, that responds with the health Report without the "redis" check.
+// It never fails nor pings redis, since restarting the service does not fix the feature toggle backend,
+// and the feature toggles already loaded keep being served.
+func (c *Checker) Liveness() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		write(w, http.StatusOK, c.report(req.Context(), false))
+	})
+}
+
+// write writes the report as json.
+func write(w http.ResponseWriter, code int, r Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(r)
+}
@@ -0,0 +1,97 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/delivery-much/dm-go-ft/featuretoggle"
+)
+
+// serve calls the handler, returning the status code and the report
+func serve(t *testing.T, h http.Handler) (int, Report) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	var r Report
+	err := json.Unmarshal(rec.Body.Bytes(), &r)
+	if err != nil {
+		t.Fatalf("Should have responded with the report, responded %s", rec.Body.String())
+	}
+	return rec.Code, r
+}
+
+func TestChecker(t *testing.T) {
+	t.Run("Should fail if the feature toggles were not loaded", func(t *testing.T) {
+		featuretoggle.Reset()
+		featuretoggle.Mock(nil)
+		defer featuretoggle.Reset()
+
+		err := Check(context.Background())
+		if !errors.Is(err, featuretoggle.ErrNotInitialized) {
+			t.Errorf("Should have returned ErrNotInitialized, returned %v", err)
+		}
+
+		code, r := serve(t, New(Config{ServeDegraded: true}).Readiness())
+		if code != http.StatusServiceUnavailable || r.Status != "fail" {
+			t.Errorf("Should have failed the readiness, responded %v %+v", code, r)
+		}
+	})
+	t.Run("Should apply the degraded policy", func(t *testing.T) {
+		featuretoggle.Mock(map[string]string{"MyBool": "true", "MyBool.type": "boolean"})
+		defer featuretoggle.Reset()
+
+		pingErr := errors.New("connection refused")
+		strict := New(Config{})
+		strict.ping = func(context.Context) error { return pingErr }
+		lenient := New(Config{ServeDegraded: true})
+		lenient.ping = strict.ping
+
+		err := strict.Check(context.Background())
+		if !errors.Is(err, ErrDegraded) || !errors.Is(err, pingErr) {
+			t.Errorf("Should have returned ErrDegraded with the ping error, returned %v", err)
+		}
+		if err := lenient.Check(context.Background()); err != nil {
+			t.Errorf("Should have served the degraded backend, returned %v", err)
+		}
+
+		code, r := serve(t, strict.Readiness())
+		if code != http.StatusServiceUnavailable || r.Status != "degraded" || r.Checks["redis"] != pingErr.Error() || r.Keys != 1 {
+			t.Errorf("Should have failed the readiness with the report, responded %v %+v", code, r)
+		}
+
+		code, r = serve(t, lenient.Readiness())
+		if code != http.StatusOK || r.Status != "degraded" || r.Checks["initialized"] != "ok" {
+			t.Errorf("Should have kept the readiness with the report, responded %v %+v", code, r)
+		}
+
+		code, _ = serve(t, strict.Liveness())
+		if code != http.StatusOK {
+			t.Errorf("Should not have failed the liveness, responded %v", code)
+		}
+	})
+	t.Run("Should not ping redis in the liveness", func(t *testing.T) {
+		featuretoggle.Mock(map[string]string{"MyBool": "true", "MyBool.type": "boolean"})
+		defer featuretoggle.Reset()
+
+		pings := 0
+		c := New(Config{})
+		c.ping = func(context.Context) error {
+			pings++
+			return nil
+		}
+
+		code, r := serve(t, c.Liveness())
+		if code != http.StatusOK || pings != 0 {
+			t.Errorf("Should have responded without pinging redis, responded %v after %d pings", code, pings)
+		}
+		if _, ok := r.Checks["redis"]; ok || r.Checks["initialized"] != "ok" || r.Keys != 1 {
+			t.Errorf("Should have responded with the report of the synchronization status, responded %+v", r)
+		}
+	})
+}
@@ -0,0 +1,225 @@
+// Package metrics exports Prometheus collectors for the feature toggle library,
+// fed by the events emitted by the library (see featuretoggle.OnEvent).
+package metrics
+
+import (
+	"errors"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/delivery-much/dm-go-ft/featuretoggle"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// namespace is the namespace of every metric
+const namespace = "featuretoggle"
+
+// collectors represents the Prometheus collectors of the feature toggle library
+type collectors struct {
+	evaluations     *prometheus.CounterVec
+	defaults        *prometheus.CounterVec
+	lastKnownGood   *prometheus.CounterVec
+	violations      *prometheus.CounterVec
+	rebuilds        prometheus.Counter
+	rebuildErrors   prometheus.Counter
+	rebuildDuration prometheus.Histogram
+	reconnects      prometheus.Counter
+	snapshotAge     prometheus.GaugeFunc
+	keys            prometheus.GaugeFunc
+
+	// the time of the last cache rebuild, in unix nanoseconds
+	lastRebuild atomic.Int64
+	// the number of feature toggles loaded in the last cache rebuild
+	lastKeys atomic.Int64
+}
+
+/*
+Register registers the feature toggle collectors in the registry, and starts collecting the feature toggle events:
+
+- featuretoggle_evaluations_total: the evaluations of the feature toggles, by key, type and reason;
+
+- featuretoggle_defaults_total: the default values returned, by key and reason;
+
+- featuretoggle_last_known_good_total: the invalid values replaced by the last known good value, by key;
+
+- featuretoggle_constraint_violations_total: the values rejected by their constraints, by key and constraint
+(counted on every cache rebuild that still finds them);
+
+- featuretoggle_cache_rebuilds_total, featuretoggle_cache_rebuild_errors_total and
+featuretoggle_cache_rebuild_duration_seconds: the cache rebuilds, their errors and duration;
+
+- featuretoggle_subscription_reconnects_total: the reconnects of the subscription to the redis updates;
+
+- featuretoggle_snapshot_age_seconds and featuretoggle_keys: the time since the last cache rebuild,
+and the number of feature toggles loaded by it.
+
+Should be called before featuretoggle.Init, so the first cache rebuild is collected.
+
+returns a function that stops collecting the events and unregisters the collectors.
+*/
+func Register(reg prometheus.Registerer) (unregister func(), err error) {
+	c := newCollectors()
+
+	all := c.all()
+	for i, col := range all {
+		err = reg.Register(col)
+		if err != nil {
+			for _, registered := range all[:i] {
+				reg.Unregister(registered)
+			}
+			return nil, err
+		}
+	}
+
+	stop := featuretoggle.OnEvent(c.observe)
+	return func() {
+		stop()
+		for _, col := range all {
+			reg.Unregister(col)
+		}
+	}, nil
+}
+
+// newCollectors creates the feature toggle collectors.
+func newCollectors() *collectors {
+	c := &collectors{
+		evaluations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "evaluations_total",
+			Help:      "Number of feature toggle evaluations, by key, type and reason.",
+		}, []string{"key", "type", "reason"}),
+		defaults: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "defaults_total",
+			Help:      "Number of default values returned, by key and reason.",
+		}, []string{"key", "reason"}),
+		lastKnownGood: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "last_known_good_total",
+			Help:      "Number of invalid values replaced by the last known good value, by key.",
+		}, []string{"key"}),
+		violations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "constraint_violations_total",
+			Help:      "Number of values rejected by their constraints, by key and constraint.",
+		}, []string{"key", "constraint"}),
+		rebuilds: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_rebuilds_total",
+			Help:      "Number of cache rebuilds.",
+		}),
+		rebuildErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_rebuild_errors_total",
+			Help:      "Number of cache rebuilds that failed to load the feature toggles.",
+		}),
+		rebuildDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "cache_rebuild_duration_seconds",
+			Help:      "Duration of the cache rebuilds.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		reconnects: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "subscription_reconnects_total",
+			Help:      "Number of reconnects of the subscription to the feature toggle updates.",
+		}),
+	}
+
+	c.snapshotAge = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "snapshot_age_seconds",
+		Help:      "Time since the last cache rebuild, NaN if the cache was not rebuilt yet.",
+	}, c.age)
+	c.keys = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "keys",
+		Help:      "Number of feature toggles loaded by the last cache rebuild.",
+	}, func() float64 {
+		return float64(c.lastKeys.Load())
+	})
+
+	return c
+}
+
+// all returns every collector.
+func (c *collectors) all() []prometheus.Collector {
+	return []prometheus.Collector{
+		c.evaluations,
+		c.defaults,
+		c.lastKnownGood,
+		c.violations,
+		c.rebuilds,
+		c.rebuildErrors,
+		c.rebuildDuration,
+		c.reconnects,
+		c.snapshotAge,
+		c.keys,
+	}
+}
+
+// observe updates the collectors with a feature toggle event.
+func (c *collectors) observe(e featuretoggle.Event) {
+	switch e.Type {
+	case featuretoggle.EventEvaluation:
+		c.evaluations.WithLabelValues(e.Key, e.ValueType, e.Reason).Inc()
+		if e.Err != nil {
+			c.defaults.WithLabelValues(e.Key, defaultReason(e.Err)).Inc()
+		}
+	case featuretoggle.EventLastKnownGood:
+		c.lastKnownGood.WithLabelValues(e.Key).Inc()
+	case featuretoggle.EventConstraintViolation:
+		c.violations.WithLabelValues(e.Key, constraint(e.Err)).Inc()
+	case featuretoggle.EventCacheRebuilt:
+		c.rebuilds.Inc()
+		c.rebuildDuration.Observe(e.Duration.Seconds())
+		c.lastRebuild.Store(e.Time.UnixNano())
+		c.lastKeys.Store(int64(e.Keys))
+	case featuretoggle.EventCacheRebuildFailed:
+		c.rebuilds.Inc()
+		c.rebuildErrors.Inc()
+		c.rebuildDuration.Observe(e.Duration.Seconds())
+	case featuretoggle.EventReconnect:
+		c.reconnects.Inc()
+	}
+}
+
+// age returns the seconds since the last cache rebuild, or NaN if the cache was not rebuilt yet.
+func (c *collectors) age() float64 {
+	last := c.lastRebuild.Load()
+	if last == 0 {
+		return math.NaN()
+	}
+
+	return time.Since(time.Unix(0, last)).Seconds()
+}
+
+// constraint returns the constraint label of a violation (ex.: "enum"), or "other" if the error is not a violation.
+func constraint(err error) string {
+	var v *featuretoggle.Violation
+	if errors.As(err, &v) {
+		return v.Constraint
+	}
+	return "other"
+}
+
+// defaultReason returns the reason label of a default value, based on the sentinel error of the feature toggle library.
+func defaultReason(err error) string {
+	switch {
+	case errors.Is(err, featuretoggle.ErrNotInitialized):
+		return "not_initialized"
+	case errors.Is(err, featuretoggle.ErrNotFound):
+		return "not_found"
+	case errors.Is(err, featuretoggle.ErrTypeMissing):
+		return "type_missing"
+	case errors.Is(err, featuretoggle.ErrTypeMismatch):
+		return "type_mismatch"
+	case errors.Is(err, featuretoggle.ErrParse):
+		return "parse_error"
+	case errors.Is(err, featuretoggle.ErrConstraintViolation):
+		return "constraint_violation"
+	}
+
+	return "other"
+}
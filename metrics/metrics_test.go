@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/delivery-much/dm-go-ft/featuretoggle"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRegister(t *testing.T) {
+	t.Run("Should collect the evaluations and the defaults", func(t *testing.T) {
+		featuretoggle.Mock(map[string]string{
+			"MyBool":      "true",
+			"MyBool.type": "boolean",
+		})
+		defer featuretoggle.Reset()
+
+		c := newCollectors()
+		stop := featuretoggle.OnEvent(c.observe)
+		defer stop()
+
+		featuretoggle.IsEnabled("MyBool", false)
+		featuretoggle.IsEnabled("MyBool", false)
+		featuretoggle.GetString("Missing", "")
+		featuretoggle.GetNumber("MyBool", 0)
+
+		cases := []struct {
+			counter  prometheus.Counter
+			expected float64
+		}{
+			{c.evaluations.WithLabelValues("MyBool", "boolean", featuretoggle.ReasonStatic), 2},
+			{c.evaluations.WithLabelValues("Missing", "", featuretoggle.ReasonError), 1},
+			{c.defaults.WithLabelValues("Missing", "not_found"), 1},
+			{c.defaults.WithLabelValues("MyBool", "type_mismatch"), 1},
+		}
+		for _, tc := range cases {
+			if actual := testutil.ToFloat64(tc.counter); actual != tc.expected {
+				t.Errorf("Expected the counter to be %v, was %v", tc.expected, actual)
+			}
+		}
+	})
+	t.Run("Should collect the last known good values and the constraint violations", func(t *testing.T) {
+		c := newCollectors()
+		stop := featuretoggle.OnEvent(c.observe)
+		defer stop()
+
+		featuretoggle.Mock(map[string]string{
+			"MyLimit":          "5",
+			"MyLimit.type":     "integer",
+			"MyLimit.max":      "10",
+			"MyLimit.fallback": "last_known_good",
+		})
+		defer featuretoggle.Reset()
+		featuretoggle.Mock(map[string]string{
+			"MyLimit":          "20",
+			"MyLimit.type":     "integer",
+			"MyLimit.max":      "10",
+			"MyLimit.fallback": "last_known_good",
+		})
+
+		if actual := testutil.ToFloat64(c.lastKnownGood.WithLabelValues("MyLimit")); actual != 1 {
+			t.Errorf("Should have counted the last known good value, counted %v", actual)
+		}
+		if actual := testutil.ToFloat64(c.violations.WithLabelValues("MyLimit", "max")); actual != 1 {
+			t.Errorf("Should have counted the constraint violation, counted %v", actual)
+		}
+	})
+	t.Run("Should collect the cache rebuilds and reconnects", func(t *testing.T) {
+		c := newCollectors()
+		if !math.IsNaN(c.age()) {
+			t.Errorf("Should have returned NaN before the first rebuild, returned %v", c.age())
+		}
+
+		c.observe(featuretoggle.Event{Type: featuretoggle.EventCacheRebuilt, Duration: time.Millisecond, Keys: 12, Time: time.Now().Add(-time.Minute)})
+		c.observe(featuretoggle.Event{Type: featuretoggle.EventCacheRebuildFailed, Err: errors.New("timeout"), Duration: time.Second})
+		c.observe(featuretoggle.Event{Type: featuretoggle.EventReconnect})
+
+		if actual := testutil.ToFloat64(c.rebuilds); actual != 2 {
+			t.Errorf("Should have counted every rebuild, counted %v", actual)
+		}
+		if actual := testutil.ToFloat64(c.rebuildErrors); actual != 1 {
+			t.Errorf("Should have counted the failed rebuild, counted %v", actual)
+		}
+		if actual := testutil.ToFloat64(c.reconnects); actual != 1 {
+			t.Errorf("Should have counted the reconnect, counted %v", actual)
+		}
+		if actual := testutil.ToFloat64(c.keys); actual != 12 {
+			t.Errorf("Should have reported the number of keys of the last rebuild, reported %v", actual)
+		}
+		if age := c.age(); age < 60 || age > 70 {
+			t.Errorf("Should have reported the time since the last rebuild, reported %v", age)
+		}
+	})
+	t.Run("Should register the collectors in the registry", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		unregister, err := Register(reg)
+		if err != nil {
+			t.Fatalf("Should have registered the collectors, returned %v", err)
+		}
+
+		_, err = Register(reg)
+		if err == nil {
+			t.Errorf("Should have failed to register the collectors again")
+		}
+
+		unregister()
+		unregister, err = Register(reg)
+		if err != nil {
+			t.Fatalf("Should have registered the collectors again after unregistering them, returned %v", err)
+		}
+		unregister()
+	})
+}
@@ -0,0 +1,297 @@
+// Package otel integrates the feature toggle library with OpenTelemetry,
+// recording spans and meters fed by the events emitted by the library (see featuretoggle.OnEvent).
+//
+// The evaluations made with the ctx accessors (like featuretoggle.IsEnabledCtx) are already added
+// to the span in the context as "feature_flag.evaluation" events, following the feature_flag semantic conventions.
+package otel
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/delivery-much/dm-go-ft/featuretoggle"
+	global "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// scope is the instrumentation scope of the tracer and the meter
+const scope = "github.com/delivery-much/dm-go-ft"
+
+// providerName is the feature_flag.provider.name of the feature toggle library
+const providerName = "dm-go-ft"
+
+// maxCommands is the maximum number of redis commands kept until the next cache rebuild
+const maxCommands = 16
+
+// Config represents the OpenTelemetry integration configuration
+type Config struct {
+	// TracerProvider is used to record the cache rebuild, redis command and reconnect spans.
+	// Uses the global tracer provider if nil.
+	TracerProvider trace.TracerProvider
+	// MeterProvider is used to publish the evaluation and sync meters.
+	// Uses the global meter provider if nil.
+	MeterProvider metric.MeterProvider
+}
+
+// integration represents the spans and meters of the feature toggle library
+type integration struct {
+	tracer trace.Tracer
+
+	evaluations     metric.Int64Counter
+	defaults        metric.Int64Counter
+	lastKnownGood   metric.Int64Counter
+	violations      metric.Int64Counter
+	rebuilds        metric.Int64Counter
+	rebuildErrors   metric.Int64Counter
+	rebuildDuration metric.Float64Histogram
+	reconnects      metric.Int64Counter
+
+	// the last redis commands, recorded as children of the span of the cache rebuild they are part of
+	commandsMu sync.Mutex
+	commands   []featuretoggle.Event
+
+	// the time of the last cache rebuild, in unix nanoseconds
+	lastRebuild atomic.Int64
+	// the number of feature toggles loaded in the last cache rebuild
+	lastKeys atomic.Int64
+}
+
+/*
+Register starts recording the feature toggle events with OpenTelemetry:
+
+- the "featuretoggle.rebuild" spans, with a child span for each redis command, and the "featuretoggle.reconnect" spans;
+
+- the featuretoggle.evaluations, featuretoggle.defaults, featuretoggle.last_known_good,
+featuretoggle.constraint_violations, featuretoggle.cache.rebuilds,
+featuretoggle.cache.rebuild.errors, featuretoggle.cache.rebuild.duration and featuretoggle.subscription.reconnects meters;
+
+- the featuretoggle.snapshot.age and featuretoggle.keys gauges.
+
+Should be called before featuretoggle.Init, so the first cache rebuild is recorded.
+
+returns a function that stops recording the events.
+*/
+func Register(c Config) (unregister func(), err error) {
+	if c.TracerProvider == nil {
+		c.TracerProvider = global.GetTracerProvider()
+	}
+	if c.MeterProvider == nil {
+		c.MeterProvider = global.GetMeterProvider()
+	}
+
+	i, reg, err := newIntegration(c)
+	if err != nil {
+		return nil, err
+	}
+
+	stop := featuretoggle.OnEvent(i.observe)
+	return func() {
+		stop()
+		_ = reg.Unregister()
+	}, nil
+}
+
+// newIntegration creates the tracer and the meters.
+// returns the registration of the gauges callback, that must be unregistered when the integration is stopped.
+func newIntegration(c Config) (*integration, metric.Registration, error) {
+	meter := c.MeterProvider.Meter(scope)
+	i := &integration{tracer: c.TracerProvider.Tracer(scope)}
+
+	var err error
+	i.evaluations, err = meter.Int64Counter("featuretoggle.evaluations",
+		metric.WithDescription("Number of feature toggle evaluations."),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	i.defaults, err = meter.Int64Counter("featuretoggle.defaults",
+		metric.WithDescription("Number of default values returned."),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	i.lastKnownGood, err = meter.Int64Counter("featuretoggle.last_known_good",
+		metric.WithDescription("Number of invalid values replaced by the last known good value."),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	i.violations, err = meter.Int64Counter("featuretoggle.constraint_violations",
+		metric.WithDescription("Number of values rejected by their constraints."),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	i.rebuilds, err = meter.Int64Counter("featuretoggle.cache.rebuilds",
+		metric.WithDescription("Number of cache rebuilds."),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	i.rebuildErrors, err = meter.Int64Counter("featuretoggle.cache.rebuild.errors",
+		metric.WithDescription("Number of cache rebuilds that failed to load the feature toggles."),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	i.rebuildDuration, err = meter.Float64Histogram("featuretoggle.cache.rebuild.duration",
+		metric.WithDescription("Duration of the cache rebuilds."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	i.reconnects, err = meter.Int64Counter("featuretoggle.subscription.reconnects",
+		metric.WithDescription("Number of reconnects of the subscription to the feature toggle updates."),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	age, err := meter.Float64ObservableGauge("featuretoggle.snapshot.age",
+		metric.WithDescription("Time since the last cache rebuild."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys, err := meter.Int64ObservableGauge("featuretoggle.keys",
+		metric.WithDescription("Number of feature toggles loaded by the last cache rebuild."),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reg, err := meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		last := i.lastRebuild.Load()
+		if last == 0 {
+			// the cache was not rebuilt yet
+			return nil
+		}
+
+		o.ObserveFloat64(age, time.Since(time.Unix(0, last)).Seconds())
+		o.ObserveInt64(keys, i.lastKeys.Load())
+		return nil
+	}, age, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return i, reg, nil
+}
+
+// observe records a feature toggle event.
+func (i *integration) observe(e featuretoggle.Event) {
+	ctx := context.Background()
+
+	switch e.Type {
+	case featuretoggle.EventEvaluation:
+		attrs := metric.WithAttributes(
+			attribute.String("feature_flag.key", e.Key),
+			attribute.String("feature_flag.provider.name", providerName),
+			attribute.String("feature_flag.type", e.ValueType),
+			attribute.String("feature_flag.result.reason", e.Reason),
+		)
+		i.evaluations.Add(ctx, 1, attrs)
+		if e.Err != nil {
+			i.defaults.Add(ctx, 1, metric.WithAttributes(
+				attribute.String("feature_flag.key", e.Key),
+				attribute.String("error.type", featuretoggle.ErrorType(e.Err)),
+			))
+		}
+	case featuretoggle.EventLastKnownGood:
+		i.lastKnownGood.Add(ctx, 1, metric.WithAttributes(attribute.String("feature_flag.key", e.Key)))
+	case featuretoggle.EventConstraintViolation:
+		constraint := "other"
+		var v *featuretoggle.Violation
+		if errors.As(e.Err, &v) {
+			constraint = v.Constraint
+		}
+		i.violations.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("feature_flag.key", e.Key),
+			attribute.String("featuretoggle.constraint", constraint),
+		))
+	case featuretoggle.EventRedisCommand:
+		i.commandsMu.Lock()
+		// the commands made outside of a rebuild (ex.: the version checks) are never drained,
+		// so only the last ones are kept
+		if len(i.commands) == maxCommands {
+			i.commands = append(i.commands[:0], i.commands[1:]...)
+		}
+		i.commands = append(i.commands, e)
+		i.commandsMu.Unlock()
+	case featuretoggle.EventCacheRebuilt, featuretoggle.EventCacheRebuildFailed:
+		i.rebuilds.Add(ctx, 1)
+		i.rebuildDuration.Record(ctx, e.Duration.Seconds())
+		if e.Type == featuretoggle.EventCacheRebuildFailed {
+			i.rebuildErrors.Add(ctx, 1)
+		} else {
+			i.lastRebuild.Store(e.Time.UnixNano())
+			i.lastKeys.Store(int64(e.Keys))
+		}
+		i.recordRebuild(e)
+	case featuretoggle.EventReconnect:
+		i.reconnects.Add(ctx, 1)
+
+		_, span := i.tracer.Start(ctx, "featuretoggle.reconnect", trace.WithTimestamp(e.Time))
+		recordError(span, e.Err)
+		span.End(trace.WithTimestamp(e.Time))
+	}
+}
+
+// recordRebuild records the span of a cache rebuild, ended when the event was emitted,
+// with a child span for each redis command made by the rebuild.
+func (i *integration) recordRebuild(e featuretoggle.Event) {
+	start := e.Time.Add(-e.Duration)
+
+	i.commandsMu.Lock()
+	commands := i.commands
+	i.commands = nil
+	i.commandsMu.Unlock()
+
+	ctx, span := i.tracer.Start(context.Background(), "featuretoggle.rebuild",
+		trace.WithTimestamp(start),
+		trace.WithAttributes(attribute.String("feature_flag.provider.name", providerName)),
+	)
+	if e.Type == featuretoggle.EventCacheRebuilt {
+		span.SetAttributes(attribute.Int("featuretoggle.keys", e.Keys))
+	}
+
+	for _, c := range commands {
+		if c.Time.Before(start) {
+			// made before the rebuild started
+			continue
+		}
+
+		_, child := i.tracer.Start(ctx, "redis "+c.Method,
+			trace.WithTimestamp(c.Time.Add(-c.Duration)),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", "redis"),
+				attribute.String("db.operation.name", c.Method),
+				attribute.String("featuretoggle.service", c.Key),
+			),
+		)
+		recordError(child, c.Err)
+		child.End(trace.WithTimestamp(c.Time))
+	}
+
+	recordError(span, e.Err)
+	span.End(trace.WithTimestamp(e.Time))
+}
+
+// recordError records the error on the span, if any.
+func recordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
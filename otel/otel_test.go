@@ -0,0 +1,203 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/delivery-much/dm-go-ft/featuretoggle"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// providers returns in-memory tracer and meter providers, with the span recorder and the metric reader
+func providers() (Config, *tracetest.SpanRecorder, *sdkmetric.ManualReader) {
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+
+	return Config{
+		TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)),
+		MeterProvider:  sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+	}, spans, reader
+}
+
+// collect returns the metrics collected by the reader, by name
+func collect(t *testing.T, reader *sdkmetric.ManualReader) map[string]metricdata.Aggregation {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	err := reader.Collect(context.Background(), &rm)
+	if err != nil {
+		t.Fatalf("Should have collected the metrics, returned %v", err)
+	}
+
+	metrics := map[string]metricdata.Aggregation{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+	return metrics
+}
+
+// sum returns the value of the first data point of the sum with the attributes, or -1 if not found
+func sum(data metricdata.Aggregation, attrs ...attribute.KeyValue) int64 {
+	s, ok := data.(metricdata.Sum[int64])
+	if !ok {
+		return -1
+	}
+
+	for _, dp := range s.DataPoints {
+		match := true
+		for _, kv := range attrs {
+			v, ok := dp.Attributes.Value(kv.Key)
+			match = match && ok && v == kv.Value
+		}
+		if match {
+			return dp.Value
+		}
+	}
+	return -1
+}
+
+func TestRegister(t *testing.T) {
+	t.Run("Should publish the evaluation meters", func(t *testing.T) {
+		featuretoggle.Mock(map[string]string{
+			"MyBool":      "true",
+			"MyBool.type": "boolean",
+		})
+		defer featuretoggle.Reset()
+
+		c, _, reader := providers()
+		unregister, err := Register(c)
+		if err != nil {
+			t.Fatalf("Should have registered the integration, returned %v", err)
+		}
+		defer unregister()
+
+		featuretoggle.IsEnabled("MyBool", false)
+		featuretoggle.IsEnabled("MyBool", false)
+		featuretoggle.GetString("Missing", "")
+
+		metrics := collect(t, reader)
+		evaluated := sum(metrics["featuretoggle.evaluations"],
+			attribute.String("feature_flag.key", "MyBool"),
+			attribute.String("feature_flag.type", "boolean"),
+			attribute.String("feature_flag.result.reason", featuretoggle.ReasonStatic),
+		)
+		if evaluated != 2 {
+			t.Errorf("Should have counted the evaluations of the key, counted %v", evaluated)
+		}
+		if actual := sum(metrics["featuretoggle.defaults"], attribute.String("error.type", "flag_not_found")); actual != 1 {
+			t.Errorf("Should have counted the default values by error type, counted %v", actual)
+		}
+	})
+	t.Run("Should publish the last known good and constraint violation meters", func(t *testing.T) {
+		c, _, reader := providers()
+		unregister, err := Register(c)
+		if err != nil {
+			t.Fatalf("Should have registered the integration, returned %v", err)
+		}
+		defer unregister()
+
+		featuretoggle.Mock(map[string]string{
+			"MyLimit":          "5",
+			"MyLimit.type":     "integer",
+			"MyLimit.max":      "10",
+			"MyLimit.fallback": "last_known_good",
+		})
+		defer featuretoggle.Reset()
+		featuretoggle.Mock(map[string]string{
+			"MyLimit":          "20",
+			"MyLimit.type":     "integer",
+			"MyLimit.max":      "10",
+			"MyLimit.fallback": "last_known_good",
+		})
+
+		metrics := collect(t, reader)
+		if actual := sum(metrics["featuretoggle.last_known_good"], attribute.String("feature_flag.key", "MyLimit")); actual != 1 {
+			t.Errorf("Should have counted the last known good value, counted %v", actual)
+		}
+		violations := sum(metrics["featuretoggle.constraint_violations"],
+			attribute.String("feature_flag.key", "MyLimit"),
+			attribute.String("featuretoggle.constraint", "max"),
+		)
+		if violations != 1 {
+			t.Errorf("Should have counted the constraint violation, counted %v", violations)
+		}
+	})
+	t.Run("Should record the rebuild spans and the sync meters", func(t *testing.T) {
+		c, spans, reader := providers()
+		i, reg, err := newIntegration(c)
+		if err != nil {
+			t.Fatalf("Should have created the integration, returned %v", err)
+		}
+		defer reg.Unregister()
+
+		end := time.Now()
+		i.observe(featuretoggle.Event{Type: featuretoggle.EventRedisCommand, Key: "MyService", Method: "HGETALL", Duration: 5 * time.Millisecond, Time: end.Add(-time.Millisecond)})
+		i.observe(featuretoggle.Event{Type: featuretoggle.EventCacheRebuilt, Duration: 10 * time.Millisecond, Keys: 12, Time: end})
+		i.observe(featuretoggle.Event{Type: featuretoggle.EventReconnect, Err: errors.New("i/o timeout"), Time: end})
+
+		ended := spans.Ended()
+		if len(ended) != 3 {
+			t.Fatalf("Should have recorded the redis command, rebuild and reconnect spans, recorded %v", len(ended))
+		}
+
+		command, rebuild, reconnect := ended[0], ended[1], ended[2]
+		if command.Name() != "redis HGETALL" || command.Parent().SpanID() != rebuild.SpanContext().SpanID() {
+			t.Errorf("Should have recorded the redis command as a child of the rebuild, recorded %s", command.Name())
+		}
+		if !rebuild.StartTime().Equal(end.Add(-10*time.Millisecond)) || !rebuild.EndTime().Equal(end) {
+			t.Errorf("Should have recorded the rebuild with its duration, recorded %v to %v", rebuild.StartTime(), rebuild.EndTime())
+		}
+		if reconnect.Name() != "featuretoggle.reconnect" || reconnect.Status().Code != codes.Error {
+			t.Errorf("Should have recorded the reconnect with its error, recorded %s %v", reconnect.Name(), reconnect.Status())
+		}
+
+		metrics := collect(t, reader)
+		if actual := sum(metrics["featuretoggle.cache.rebuilds"]); actual != 1 {
+			t.Errorf("Should have counted the rebuild, counted %v", actual)
+		}
+		if actual := sum(metrics["featuretoggle.subscription.reconnects"]); actual != 1 {
+			t.Errorf("Should have counted the reconnect, counted %v", actual)
+		}
+
+		keys, ok := metrics["featuretoggle.keys"].(metricdata.Gauge[int64])
+		if !ok || len(keys.DataPoints) != 1 || keys.DataPoints[0].Value != 12 {
+			t.Errorf("Should have reported the number of keys of the last rebuild, reported %+v", metrics["featuretoggle.keys"])
+		}
+	})
+	t.Run("Should not attach the redis commands made before the rebuild", func(t *testing.T) {
+		c, spans, _ := providers()
+		i, reg, err := newIntegration(c)
+		if err != nil {
+			t.Fatalf("Should have created the integration, returned %v", err)
+		}
+		defer reg.Unregister()
+
+		end := time.Now()
+		for n := 0; n < 100; n++ {
+			i.observe(featuretoggle.Event{Type: featuretoggle.EventRedisCommand, Key: "MyService", Method: "HGET", Duration: time.Millisecond, Time: end.Add(-time.Minute)})
+		}
+		if len(i.commands) != maxCommands {
+			t.Errorf("Should have kept only the last %d redis commands, kept %d", maxCommands, len(i.commands))
+		}
+
+		i.observe(featuretoggle.Event{Type: featuretoggle.EventRedisCommand, Key: "MyService", Method: "HGETALL", Duration: 5 * time.Millisecond, Time: end.Add(-time.Millisecond)})
+		i.observe(featuretoggle.Event{Type: featuretoggle.EventCacheRebuilt, Duration: 10 * time.Millisecond, Keys: 12, Time: end})
+
+		ended := spans.Ended()
+		if len(ended) != 2 || ended[0].Name() != "redis HGETALL" || ended[1].Name() != "featuretoggle.rebuild" {
+			t.Errorf("Should have recorded only the redis command of the rebuild, recorded %v spans", len(ended))
+		}
+		if len(i.commands) != 0 {
+			t.Errorf("Should have drained the redis commands, kept %d", len(i.commands))
+		}
+	})
+}